@@ -0,0 +1,135 @@
+package main
+
+import "strings"
+
+// atomNamespace is the namespace of Atom 1.0 elements. Element tags have to
+// spell it out so same-named extensions like <media:content> don't match.
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type AtomFeed struct {
+	Lang     string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title    string       `xml:"http://www.w3.org/2005/Atom title"`
+	Authors  []AtomPerson `xml:"http://www.w3.org/2005/Atom author"`
+	Subtitle string       `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Links    []AtomLink   `xml:"http://www.w3.org/2005/Atom link"`
+	Entries  []AtomEntry  `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type AtomEntry struct {
+	ID        string     `xml:"http://www.w3.org/2005/Atom id"`
+	Title     string     `xml:"http://www.w3.org/2005/Atom title"`
+	Links     []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Updated   string     `xml:"http://www.w3.org/2005/Atom updated"`
+	Published string     `xml:"http://www.w3.org/2005/Atom published"`
+	Summary   AtomText   `xml:"http://www.w3.org/2005/Atom summary"`
+	Content   AtomText   `xml:"http://www.w3.org/2005/Atom content"`
+
+	Authors    []AtomPerson   `xml:"http://www.w3.org/2005/Atom author"`
+	Categories []AtomCategory `xml:"http://www.w3.org/2005/Atom category"`
+
+	MediaContent []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup   []MediaContent `xml:"http://search.yahoo.com/mrss/ group>content"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"http://www.w3.org/2005/Atom name"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// toRSSFeed maps an Atom feed onto the RSS model so the aggregator can treat
+// every format the same way.
+func (f AtomFeed) toRSSFeed() *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Title
+	feed.Channel.Link = alternateLink(f.Links)
+	feed.Channel.Description = f.Subtitle
+	feed.Channel.Language = f.Lang
+
+	for _, entry := range f.Entries {
+		item := RSSItem{
+			Guid:        entry.ID,
+			Title:       entry.Title,
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary.text(),
+			PubDate:     entry.Published,
+			Content:     entry.Content.text(),
+
+			MediaContent: entry.MediaContent,
+			MediaGroup:   entry.MediaGroup,
+		}
+		// entries without authors inherit the feed's
+		authors := entry.Authors
+		if len(authors) == 0 {
+			authors = f.Authors
+		}
+		item.Author = personNames(authors)
+		for _, category := range entry.Categories {
+			item.Categories = append(item.Categories, firstNonEmpty(category.Label, category.Term))
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{
+					URL:    link.Href,
+					Length: link.Length,
+					Type:   link.Type,
+				})
+			}
+		}
+		if item.Description == "" {
+			item.Description = entry.Content.text()
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return &feed
+}
+
+// alternateLink returns the link pointing at the html version of the resource.
+// A link without a rel attribute is treated as rel="alternate" per RFC 4287.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func personNames(people []AtomPerson) string {
+	var names []string
+	for _, person := range people {
+		if name := strings.TrimSpace(person.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// text returns the element's content. Text and escaped html constructs are
+// decoded by the xml package, xhtml constructs are returned as raw markup.
+func (t AtomText) text() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Body)
+}
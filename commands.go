@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/carsondecker/gator/internal/database"
@@ -17,12 +18,12 @@ type command struct {
 }
 
 type commands struct {
-	cmds map[string]func(*state, command) error
+	cmds map[string]func(context.Context, *state, command) error
 }
 
 func initCommands() (*commands, error) {
 	cmds := &commands{
-		cmds: make(map[string]func(*state, command) error),
+		cmds: make(map[string]func(context.Context, *state, command) error),
 	}
 
 	err := cmds.register("login", handlerLogin)
@@ -80,10 +81,35 @@ func initCommands() (*commands, error) {
 		return nil, err
 	}
 
+	err = cmds.register("post", middlewareLoggedIn(handlerPost))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmds.register("health", handlerHealth)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmds.register("download", middlewareLoggedIn(handlerDownload))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmds.register("fullarticle", middlewareLoggedIn(handlerFullArticle))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmds.register("enablefeed", middlewareLoggedIn(handlerEnableFeed))
+	if err != nil {
+		return nil, err
+	}
+
 	return cmds, nil
 }
 
-func (c *commands) register(name string, f func(*state, command) error) error {
+func (c *commands) register(name string, f func(context.Context, *state, command) error) error {
 	if _, ok := c.cmds[name]; ok {
 		return errors.New("command already exists")
 	}
@@ -92,20 +118,20 @@ func (c *commands) register(name string, f func(*state, command) error) error {
 	return nil
 }
 
-func (c *commands) run(s *state, cmd command) error {
+func (c *commands) run(ctx context.Context, s *state, cmd command) error {
 	if _, ok := c.cmds[cmd.name]; !ok {
 		return errors.New("command does not exist")
 	}
 
-	return c.cmds[cmd.name](s, cmd)
+	return c.cmds[cmd.name](ctx, s, cmd)
 }
 
-func handlerLogin(s *state, cmd command) error {
+func handlerLogin(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) == 0 {
 		return errors.New("login command expects a username argument")
 	}
 
-	if _, err := s.db.GetUser(context.Background(), cmd.args[0]); err != nil {
+	if _, err := s.db.GetUser(ctx, cmd.args[0]); err != nil {
 		return errors.New("user doesn't exists")
 	}
 
@@ -118,16 +144,16 @@ func handlerLogin(s *state, cmd command) error {
 	return nil
 }
 
-func handlerRegister(s *state, cmd command) error {
+func handlerRegister(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) == 0 {
 		return errors.New("register command expects a username argument")
 	}
 
-	if _, err := s.db.GetUser(context.Background(), cmd.args[0]); err == nil {
+	if _, err := s.db.GetUser(ctx, cmd.args[0]); err == nil {
 		return errors.New("user already exists")
 	}
 
-	_, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
+	_, err := s.db.CreateUser(ctx, database.CreateUserParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -147,16 +173,16 @@ func handlerRegister(s *state, cmd command) error {
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	err := s.db.ResetUsers(context.Background())
+func handlerReset(ctx context.Context, s *state, cmd command) error {
+	err := s.db.ResetUsers(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func handlerUsers(s *state, cmd command) error {
-	users, err := s.db.GetUsers(context.Background())
+func handlerUsers(ctx context.Context, s *state, cmd command) error {
+	users, err := s.db.GetUsers(ctx)
 	if err != nil {
 		return err
 	}
@@ -172,7 +198,7 @@ func handlerUsers(s *state, cmd command) error {
 	return nil
 }
 
-func handlerAgg(s *state, cmd command) error {
+func handlerAgg(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) < 1 {
 		return errors.New("agg command requires time between requests argument")
 	}
@@ -186,21 +212,45 @@ func handlerAgg(s *state, cmd command) error {
 		return errors.New("agg command requires at least 10s between requests")
 	}
 
-	return scrapeFeeds(s, time_between_reqs)
+	concurrency := 1
+	if len(cmd.args) > 1 {
+		concurrency, err = strconv.Atoi(cmd.args[1])
+		if err != nil {
+			return errors.New("could not parse entered concurrency")
+		}
+		if concurrency < 1 {
+			return errors.New("agg command requires a concurrency of at least 1")
+		}
+	}
+
+	return scrapeFeeds(ctx, s, time_between_reqs, concurrency)
 }
 
-func handlerAddFeed(s *state, cmd command, user database.User) error {
+func handlerAddFeed(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 2 {
 		return errors.New("addfeed command requires name and url arguments")
 	}
 
-	feed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      cmd.args[0],
-		Url:       cmd.args[1],
-		UserID:    user.ID,
+	discovered, err := s.fetcher.discoverFeed(ctx, cmd.args[1])
+	if err != nil {
+		return err
+	}
+	if discovered.url != cmd.args[1] {
+		fmt.Printf("using feed %s for %s\n", discovered.url, cmd.args[1])
+	}
+	feedURL, rssFeed := discovered.url, discovered.feed
+
+	feed, err := s.db.CreateFeed(ctx, database.CreateFeedParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Name:        cmd.args[0],
+		Url:         feedURL,
+		UserID:      user.ID,
+		Title:       strings.TrimSpace(rssFeed.Channel.Title),
+		Description: strings.TrimSpace(rssFeed.Channel.Description),
+		SiteUrl:     strings.TrimSpace(rssFeed.Channel.Link),
+		Language:    strings.TrimSpace(rssFeed.Channel.Language),
 	})
 
 	if err != nil {
@@ -209,7 +259,7 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 
 	fmt.Printf("feed %s created with url %s for user %s\n", feed.Name, feed.Url, user.Name)
 
-	_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -224,30 +274,53 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFeeds(s *state, cmd command) error {
-	feeds, err := s.db.GetFeedsWithUser(context.Background())
+func handlerFeeds(ctx context.Context, s *state, cmd command) error {
+	feeds, err := s.db.GetFeedsWithUser(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, feed := range feeds {
 		fmt.Printf("feed %s with url %s for user %s\n", feed.Name, feed.Url, feed.UserName)
+		if feed.Title != "" {
+			fmt.Printf("  title: %s\n", feed.Title)
+		}
+		if feed.Description != "" {
+			fmt.Printf("  description: %s\n", feed.Description)
+		}
+		if feed.SiteUrl != "" {
+			fmt.Printf("  site: %s\n", feed.SiteUrl)
+		}
+		if feed.Language != "" {
+			fmt.Printf("  language: %s\n", feed.Language)
+		}
+		if feed.FullArticle {
+			fmt.Println("  fetching full articles")
+		}
+		if feed.DisabledAt.Valid {
+			fmt.Printf("  disabled since %s, re-enable with enablefeed\n", feed.DisabledAt.Time.Format(time.DateTime))
+		} else if feed.ConsecutiveFailures > 0 {
+			fmt.Printf("  failing: %d consecutive failures, next attempt at %s\n", feed.ConsecutiveFailures, feed.NextFetchAt.Time.Format(time.DateTime))
+		}
+		if feed.LastError.Valid {
+			fmt.Printf("  last error: %s\n", feed.LastError.String)
+		}
 	}
 
 	return nil
 }
 
-func handlerFollow(s *state, cmd command, user database.User) error {
+func handlerFollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
 		return errors.New("follow command requires url argument")
 	}
 
-	feed, err := s.db.GetFeedByUrl(context.Background(), cmd.args[0])
+	feed, err := s.db.GetFeedByUrl(ctx, cmd.args[0])
 	if err != nil {
 		return err
 	}
 
-	feedFollow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	feedFollow, err := s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -264,8 +337,8 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFollowing(s *state, cmd command) error {
-	feedFollows, err := s.db.GetFeedFollowsForUser(context.Background(), s.config.CurrentUserName)
+func handlerFollowing(ctx context.Context, s *state, cmd command) error {
+	feedFollows, err := s.db.GetFeedFollowsForUser(ctx, s.config.CurrentUserName)
 	if err != nil {
 		return err
 	}
@@ -277,17 +350,17 @@ func handlerFollowing(s *state, cmd command) error {
 	return nil
 }
 
-func handlerUnfollow(s *state, cmd command, user database.User) error {
+func handlerUnfollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
 		return errors.New("unfollow command requires url argument")
 	}
 
-	feed, err := s.db.GetFeedByUrl(context.Background(), cmd.args[0])
+	feed, err := s.db.GetFeedByUrl(ctx, cmd.args[0])
 	if err != nil {
 		return err
 	}
 
-	err = s.db.UnfollowFeedForUser(context.Background(), database.UnfollowFeedForUserParams{
+	err = s.db.UnfollowFeedForUser(ctx, database.UnfollowFeedForUserParams{
 		UserID: user.ID,
 		FeedID: feed.ID,
 	})
@@ -300,38 +373,228 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerBrowse(s *state, cmd command, user database.User) error {
-	browseLimit := 2
-	if len(cmd.args) != 0 {
-		var err error
-		browseLimit, err = strconv.Atoi(cmd.args[0])
-		if err != nil {
-			return err
+func handlerBrowse(ctx context.Context, s *state, cmd command, user database.User) error {
+	params := database.GetPostsForUserParams{
+		UserID:    user.ID,
+		PostLimit: 2,
+	}
+	for i := 0; i < len(cmd.args); i++ {
+		switch arg := cmd.args[i]; arg {
+		case "--author", "--category":
+			if i+1 == len(cmd.args) {
+				return fmt.Errorf("browse %s option requires a value", arg)
+			}
+			i++
+			if arg == "--author" {
+				params.Author = cmd.args[i]
+			} else {
+				params.Category = cmd.args[i]
+			}
+		default:
+			browseLimit, err := strconv.Atoi(arg)
+			if err != nil {
+				return err
+			}
+			params.PostLimit = int32(browseLimit)
 		}
 	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(browseLimit),
+	posts, err := s.db.GetPostsForUser(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	enclosures, err := s.db.GetEnclosuresForPosts(ctx, database.GetEnclosuresForPostsParams{
+		UserID:  user.ID,
+		PostIds: postIDs,
 	})
 	if err != nil {
 		return err
 	}
+	enclosuresByPost := make(map[uuid.UUID][]database.GetEnclosuresForPostsRow)
+	for _, enclosure := range enclosures {
+		enclosuresByPost[enclosure.PostID] = append(enclosuresByPost[enclosure.PostID], enclosure)
+	}
 
 	for _, post := range posts {
-		fmt.Println(post.Title)
+		if post.Revisions > 0 {
+			fmt.Printf("%s (updated)\n", post.Title)
+		} else {
+			fmt.Println(post.Title)
+		}
+		fmt.Printf("  %s\n", post.Url)
+		if post.Author != "" {
+			fmt.Printf("  by %s\n", post.Author)
+		}
+		description := renderHTML(post.Description, post.Url, terminalWidth()-4)
+		if description != "" {
+			fmt.Println(indent(description, "    "))
+		}
+		for _, enclosure := range enclosuresByPost[post.ID] {
+			fmt.Printf("  enclosure: %s%s\n", enclosure.Url, enclosureDetails(enclosure))
+			if enclosure.DownloadPath.Valid {
+				fmt.Printf("    available offline at %s\n", enclosure.DownloadPath.String)
+			}
+		}
+	}
+
+	return nil
+}
+
+func handlerPost(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("post command requires url argument")
+	}
+
+	post, err := s.db.GetPostForUserByUrl(ctx, database.GetPostForUserByUrlParams{
+		UserID: user.ID,
+		Url:    cmd.args[0],
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(post.Title)
+	fmt.Println(post.Url)
+	if post.Author != "" {
+		fmt.Printf("by %s\n", post.Author)
+	}
+	if post.PublishedAtEstimated {
+		fmt.Printf("published around %s (estimated)\n", post.PublishedAt.Format(time.DateTime))
+	} else {
+		fmt.Printf("published %s\n", post.PublishedAt.Format(time.DateTime))
+	}
+	if post.Revisions > 0 {
+		fmt.Printf("updated %d times, last at %s\n", post.Revisions, post.UpdatedAt.Format(time.DateTime))
+	}
+	fmt.Println()
+
+	fmt.Println(renderHTML(firstNonEmpty(post.Article, post.Content, post.Description), post.Url, terminalWidth()))
+
+	return nil
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func enclosureDetails(enclosure database.GetEnclosuresForPostsRow) string {
+	var details []string
+	if enclosure.MimeType != "" {
+		details = append(details, enclosure.MimeType)
+	}
+	if enclosure.Length > 0 {
+		details = append(details, fmt.Sprintf("%.1f MB", float64(enclosure.Length)/1e6))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(details, ", ") + ")"
+}
+
+func handlerFullArticle(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 || (cmd.args[1] != "on" && cmd.args[1] != "off") {
+		return errors.New("fullarticle command requires url and on or off arguments")
+	}
+
+	feed, err := s.db.GetFeedByUrl(ctx, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if feed.UserID != user.ID {
+		return errors.New("only the user who added a feed can change it")
+	}
+
+	err = s.db.SetFeedFullArticle(ctx, database.SetFeedFullArticleParams{
+		ID:          feed.ID,
+		FullArticle: cmd.args[1] == "on",
+	})
+	if err != nil {
+		return err
+	}
+
+	if cmd.args[1] == "on" {
+		fmt.Printf("full articles will be fetched for new posts of %s\n", feed.Url)
+	} else {
+		fmt.Printf("full articles will no longer be fetched for %s\n", feed.Url)
+	}
+	return nil
+}
+
+func handlerEnableFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("enablefeed command requires url argument")
+	}
+
+	feed, err := s.db.GetFeedByUrl(ctx, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if feed.UserID != user.ID {
+		return errors.New("only the user who added a feed can change it")
+	}
+	if !feed.DisabledAt.Valid {
+		return fmt.Errorf("feed %s is not disabled", feed.Url)
+	}
+
+	err = s.db.EnableFeed(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("feed %s enabled, it will be fetched on the next aggregation\n", feed.Url)
+	return nil
+}
+
+func handlerHealth(ctx context.Context, s *state, cmd command) error {
+	feeds, err := s.db.GetFeedsWithUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("feed %s with url %s: %s\n", feed.Name, feed.Url, feedHealth(feed))
+		if feed.LastError.Valid {
+			fmt.Printf("  last error: %s\n", feed.LastError.String)
+		}
 	}
 
 	return nil
 }
 
-func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
-	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.config.CurrentUserName)
+func feedHealth(feed database.GetFeedsWithUserRow) string {
+	switch {
+	case feed.DisabledAt.Valid:
+		return fmt.Sprintf("disabled since %s", feed.DisabledAt.Time.Format(time.DateTime))
+	case feed.ConsecutiveFailures == 0:
+		return "ok"
+	case feed.LastStatusCode.Valid:
+		return fmt.Sprintf("%s (%d), %d consecutive failures, next attempt at %s",
+			statusClass(int(feed.LastStatusCode.Int32)), feed.LastStatusCode.Int32,
+			feed.ConsecutiveFailures, feed.NextFetchAt.Time.Format(time.DateTime))
+	default:
+		return fmt.Sprintf("failing, %d consecutive failures, next attempt at %s",
+			feed.ConsecutiveFailures, feed.NextFetchAt.Time.Format(time.DateTime))
+	}
+}
+
+func middlewareLoggedIn(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(context.Context, *state, command) error {
+	return func(ctx context.Context, s *state, cmd command) error {
+		user, err := s.db.GetUser(ctx, s.config.CurrentUserName)
 		if err != nil {
 			return err
 		}
 
-		return handler(s, cmd, user)
+		return handler(ctx, s, cmd, user)
 	}
 }
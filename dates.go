@@ -0,0 +1,132 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dateLayouts are tried in order after a date has been normalised by
+// parsePubDate, so they never contain a weekday or a zone name.
+var dateLayouts = []string{
+	// RFC 822 and RFC 1123 with the many variations found in RSS feeds
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 January 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006",
+	"January 2, 2006",
+
+	// RFC 850, obsoleted by RFC 1036 but still produced by old servers
+	"02-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+	"02-Jan-06 15:04:05",
+
+	// RFC 3339 and the ISO 8601 forms used by Atom, RDF and JSON Feed
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+
+	// ANSI C asctime and unix date output
+	"Jan _2 15:04:05 2006",
+	"Jan _2 15:04:05 -0700 2006",
+}
+
+// zoneOffsets maps the zone names allowed by RFC 822 to their offsets. Go only
+// understands zone abbreviations for the local zone and parses the rest as
+// UTC, which would shift posts by hours.
+var zoneOffsets = map[string]string{
+	"UT":  "+0000",
+	"UTC": "+0000",
+	"GMT": "+0000",
+	"Z":   "+0000",
+	"EST": "-0500",
+	"EDT": "-0400",
+	"CST": "-0600",
+	"CDT": "-0500",
+	"MST": "-0700",
+	"MDT": "-0600",
+	"PST": "-0800",
+	"PDT": "-0700",
+}
+
+var (
+	leadingWeekday = regexp.MustCompile(`(?i)^(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?,?\s+`)
+	trailingNote   = regexp.MustCompile(`\s*\([^)]*\)$`)
+	numericOffset  = regexp.MustCompile(`^[+-]\d{2}:?\d{2}$`)
+	zoneName       = regexp.MustCompile(`^[A-Z]{3,5}$`)
+)
+
+// parsePubDate parses a publication date in any of the formats seen in feeds.
+// Dates that are missing or can't be parsed fall back to fetchedAt and are
+// reported as estimated. Times are returned in the local zone, matching the
+// other timestamps stored by the aggregator.
+func parsePubDate(value string, fetchedAt time.Time) (parsed time.Time, estimated bool) {
+	value = normalizeDate(value)
+	if value == "" {
+		return fetchedAt, true
+	}
+
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed.Local(), false
+		}
+	}
+
+	return fetchedAt, true
+}
+
+// normalizeDate strips the weekday, which is often wrong or misspelled, and
+// comments such as "(PST)", and replaces zone names with numeric offsets
+// wherever they appear, since unix date output puts the zone before the year.
+func normalizeDate(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	value = leadingWeekday.ReplaceAllString(value, "")
+	value = trailingNote.ReplaceAllString(value, "")
+
+	fields := strings.Fields(value)
+	normalized := make([]string, 0, len(fields))
+	for i, field := range fields {
+		offset, known := zoneOffsets[strings.ToUpper(field)]
+		switch {
+		case i > 0 && numericOffset.MatchString(fields[i-1]) && (known || zoneName.MatchString(field)):
+			// a redundant name after a numeric offset, as in "+0000 GMT"
+		case known && i > 0:
+			normalized = append(normalized, offset)
+		case zoneName.MatchString(field) && !isMonth(field):
+			// unknown zone names are ambiguous, so treat the time as UTC
+		default:
+			normalized = append(normalized, field)
+		}
+	}
+
+	return strings.Join(normalized, " ")
+}
+
+func isMonth(value string) bool {
+	for _, layout := range []string{"Jan", "January"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
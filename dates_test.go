@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	fetchedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	want := time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{"RFC 1123 with numeric zone", "Mon, 02 Jan 2006 15:04:05 -0700", want},
+		{"RFC 1123 with zone name", "Mon, 02 Jan 2006 15:04:05 MST", want},
+		{"obsolete US zone", "Mon, 02 Jan 2006 17:04:05 EST", want},
+		{"daylight saving zone", "Mon, 02 Jan 2006 15:04:05 PDT", want},
+		{"pacific standard zone", "Mon, 02 Jan 2006 14:04:05 PST", want},
+		{"GMT", "Mon, 02 Jan 2006 22:04:05 GMT", want},
+		{"UT", "Mon, 02 Jan 2006 22:04:05 UT", want},
+		{"single digit day", "Mon, 2 Jan 2006 15:04:05 -0700", want},
+		{"missing weekday", "02 Jan 2006 15:04:05 -0700", want},
+		{"misspelled weekday", "Monday, 02 Jan 2006 15:04:05 -0700", want},
+		{"wrong weekday", "Fri, 02 Jan 2006 15:04:05 -0700", want},
+		{"without seconds", "Mon, 02 Jan 2006 15:04 -0700", want.Add(-5 * time.Second)},
+		{"two digit year", "Mon, 02 Jan 06 15:04:05 -0700", want},
+		{"full month name", "2 January 2006 15:04:05 -0700", want},
+		{"zone comment", "Mon, 02 Jan 2006 15:04:05 -0700 (MST)", want},
+		{"redundant zone name", "Mon, 02 Jan 2006 22:04:05 +0000 GMT", want},
+		{"unknown zone name", "Mon, 02 Jan 2006 22:04:05 CEST", want},
+		{"extra whitespace", "  Mon,  02 Jan 2006\t15:04:05 -0700 ", want},
+		{"RFC 3339", "2006-01-02T15:04:05-07:00", want},
+		{"RFC 3339 in UTC", "2006-01-02T22:04:05Z", want},
+		{"RFC 3339 with fractional seconds", "2006-01-02T22:04:05.000Z", want},
+		{"ISO 8601 basic offset", "2006-01-02T15:04:05-0700", want},
+		{"ISO 8601 without seconds", "2006-01-02T15:04-07:00", want.Add(-5 * time.Second)},
+		{"ISO 8601 with space", "2006-01-02 22:04:05Z", want},
+		{"date only", "2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"unix date", "Mon Jan  2 15:04:05 MST 2006", want},
+		{"ANSI C", "Mon Jan  2 22:04:05 2006", want},
+		{"RFC 850", "Monday, 02-Jan-06 15:04:05 MST", want},
+		{"RFC 850 in GMT", "Monday, 02-Jan-06 22:04:05 GMT", want},
+		{"month first", "Jan 2, 2006 15:04:05 -0700", want},
+		{"may is a month not a zone", "Tue, 02 May 2006 22:04:05 +0000", time.Date(2006, 5, 2, 22, 4, 5, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, estimated := parsePubDate(tt.value, fetchedAt)
+			if estimated {
+				t.Fatalf("parsePubDate(%q) fell back to the fetch time", tt.value)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.value, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePubDateFallsBackToFetchTime(t *testing.T) {
+	fetchedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{"", "   ", "yesterday", "32 Jan 2006 15:04:05 -0700", "2006-13-01"} {
+		got, estimated := parsePubDate(value, fetchedAt)
+		if !estimated || !got.Equal(fetchedAt) {
+			t.Errorf("parsePubDate(%q) = %v, %t, want fetch time and estimated", value, got, estimated)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 EST", "02 Jan 2006 15:04:05 -0500"},
+		{"Mon Jan  2 15:04:05 MST 2006", "Jan 2 15:04:05 -0700 2006"},
+		{"Monday, 02-Jan-06 15:04:05 GMT", "02-Jan-06 15:04:05 +0000"},
+		{"Mon, 02 Jan 2006 15:04:05 -0700 (MST)", "02 Jan 2006 15:04:05 -0700"},
+		{"Mon, 02 Jan 2006 15:04:05 +0000 UTC", "02 Jan 2006 15:04:05 +0000"},
+		{"Mon, 02 Jan 2006 15:04:05 AEST", "02 Jan 2006 15:04:05"},
+		{"02 MAY 2006 15:04:05 z", "02 MAY 2006 15:04:05 +0000"},
+		{"2006-01-02T15:04:05Z", "2006-01-02T15:04:05Z"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeDate(tt.value); got != tt.want {
+			t.Errorf("normalizeDate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var feedMediaTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+	"application/rdf+xml",
+}
+
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/feed.xml",
+	"/rss.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// discoveredFeed is a feed found by discoverFeed along with the url it should
+// be stored under.
+type discoveredFeed struct {
+	url  string
+	feed *RSSFeed
+}
+
+// discoverFeed resolves a url that may point at a site's html page rather
+// than its feed, returning the parsed feed so it doesn't have to be downloaded
+// again. Html pages are searched for advertised feeds before falling back to
+// commonly used feed paths. Permanent redirects are followed to the url the
+// feed now lives at.
+func (f *fetcher) discoverFeed(ctx context.Context, pageURL string) (*discoveredFeed, error) {
+	page, err := f.get(ctx, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !page.isHTML() {
+		return parseDiscoveredFeed(pageURL, page)
+	}
+
+	feedURLs, err := findFeedLinks(page.url, page.body)
+	if err != nil {
+		return nil, err
+	}
+	if len(feedURLs) > 1 {
+		fmt.Printf("found %d feeds on %s, using the first that works:\n", len(feedURLs), pageURL)
+		for _, feedURL := range feedURLs {
+			fmt.Printf("  %s\n", feedURL)
+		}
+	}
+
+	// advertised feeds can be stale or broken, so each one is tried in turn
+	// before guessing at commonly used paths
+	candidates := feedURLs
+	for _, path := range commonFeedPaths {
+		candidate := page.url.ResolveReference(&url.URL{Path: path}).String()
+		if !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	for _, candidate := range candidates {
+		candidatePage, err := f.get(ctx, candidate, nil)
+		if err != nil || candidatePage.isHTML() {
+			continue
+		}
+		if discovered, err := parseDiscoveredFeed(candidate, candidatePage); err == nil {
+			return discovered, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is a web page and no feed could be found for it", pageURL)
+}
+
+func parseDiscoveredFeed(feedURL string, res *response) (*discoveredFeed, error) {
+	if res.statusCode == http.StatusNotModified {
+		return nil, fmt.Errorf("%s is not a valid feed: unexpected %d response", feedURL, res.statusCode)
+	}
+
+	feed, err := parseFeed(res.contentType, res.body)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid feed: %w", feedURL, err)
+	}
+
+	return &discoveredFeed{
+		url:  firstNonEmpty(res.movedTo, feedURL),
+		feed: feed,
+	}, nil
+}
+
+// findFeedLinks returns the absolute urls of every
+// <link rel="alternate" type="..."> feed advertised by an html page.
+func findFeedLinks(base *url.URL, body []byte) ([]string, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New("could not parse html page")
+	}
+
+	var feedURLs []string
+	for node := range doc.Descendants() {
+		if node.Type != html.ElementNode || node.DataAtom != atom.Link {
+			continue
+		}
+
+		rel := strings.Fields(strings.ToLower(attr(node, "rel")))
+		mediaType := strings.ToLower(strings.TrimSpace(attr(node, "type")))
+		href := strings.TrimSpace(attr(node, "href"))
+		if href == "" || !slices.Contains(rel, "alternate") || !slices.Contains(feedMediaTypes, mediaType) {
+			continue
+		}
+
+		feedURL, err := base.Parse(href)
+		if err != nil {
+			continue
+		}
+		if !slices.Contains(feedURLs, feedURL.String()) {
+			feedURLs = append(feedURLs, feedURL.String())
+		}
+	}
+
+	return feedURLs, nil
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/carsondecker/gator/internal/config"
+)
+
+func TestFindFeedLinks(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "absolute and relative hrefs",
+			page: `<html><head>
+				<link rel="alternate" type="application/rss+xml" href="https://feeds.example.com/rss">
+				<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+				<link rel="alternate" type="application/feed+json" href="feed.json">
+				<link rel="alternate" type="application/rdf+xml" href="../index.rdf">
+			</head></html>`,
+			want: []string{
+				"https://feeds.example.com/rss",
+				"https://example.com/atom.xml",
+				"https://example.com/blog/feed.json",
+				"https://example.com/index.rdf",
+			},
+		},
+		{
+			name: "rel and type are case insensitive",
+			page: `<link rel="Alternate Home" type=" Application/RSS+XML " href="/rss">`,
+			want: []string{"https://example.com/rss"},
+		},
+		{
+			name: "unsupported types and other rels are ignored",
+			page: `<head>
+				<link rel="alternate" type="application/json" href="/wp-json/">
+				<link rel="alternate" type="text/html" href="/fr/">
+				<link rel="stylesheet" type="application/rss+xml" href="/style">
+				<link rel="alternate" href="/untyped">
+				<link rel="alternate" type="application/rss+xml" href="">
+			</head>`,
+			want: nil,
+		},
+		{
+			name: "duplicates are listed once",
+			page: `<head>
+				<link rel="alternate" type="application/rss+xml" href="/feed">
+				<link rel="alternate" type="application/rss+xml" href="https://example.com/feed">
+			</head>`,
+			want: []string{"https://example.com/feed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findFeedLinks(base, []byte(tt.page))
+			if err != nil {
+				t.Fatalf("findFeedLinks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findFeedLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<head>
+				<link rel="alternate" type="application/rss+xml" href="/broken.xml">
+				<link rel="alternate" type="application/atom+xml" href="/html.xml">
+				<link rel="alternate" type="application/atom+xml" href="/atom">
+			</head>`))
+		case "/html.xml":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>not a feed</body></html>"))
+		case "/atom":
+			w.Header().Set("Content-Type", "application/atom+xml")
+			w.Write(readFixture(t, "atom.xml"))
+		case "/plain":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>no feeds advertised</body></html>"))
+		case "/feed.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write(readFixture(t, "rss.xml"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newFetcher(config.Config{})
+
+	tests := []struct {
+		name    string
+		pageURL string
+		wantURL string
+	}{
+		{"advertised feeds are tried in turn", server.URL + "/", server.URL + "/atom"},
+		{"falls back to common paths", server.URL + "/plain", server.URL + "/feed.xml"},
+		{"feed urls are used as is", server.URL + "/atom", server.URL + "/atom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discovered, err := f.discoverFeed(context.Background(), tt.pageURL)
+			if err != nil {
+				t.Fatalf("discoverFeed() error = %v", err)
+			}
+			if discovered.url != tt.wantURL {
+				t.Errorf("discoverFeed() url = %s, want %s", discovered.url, tt.wantURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/carsondecker/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const defaultDownloadKeepEpisodes = 5
+
+func handlerDownload(ctx context.Context, s *state, cmd command, user database.User) error {
+	keep := s.config.DownloadKeepEpisodes
+	if keep <= 0 {
+		keep = defaultDownloadKeepEpisodes
+	}
+	if len(cmd.args) > 0 {
+		var err error
+		keep, err = strconv.Atoi(cmd.args[0])
+		if err != nil || keep < 1 {
+			return errors.New("download command expects the number of episodes to keep per feed")
+		}
+	}
+
+	downloadDir, err := s.config.GetDownloadDir()
+	if err != nil {
+		return err
+	}
+
+	episodes, err := s.db.GetLatestEpisodesForUser(ctx, database.GetLatestEpisodesForUserParams{
+		UserID: user.ID,
+		Keep:   int64(keep),
+	})
+	if err != nil {
+		return err
+	}
+
+	downloaded := 0
+	for _, episode := range episodes {
+		if episode.DownloadID.Valid {
+			continue
+		}
+
+		episodePath := filepath.Join(downloadDir, sanitizeFilename(episode.FeedName), episodeFilename(episode))
+		fmt.Printf("downloading %s: %s\n", episode.FeedName, episode.PostTitle)
+
+		size, err := s.fetcher.download(ctx, episode.Url, episodePath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fmt.Printf("  failed to download %s: %v\n", episode.Url, err)
+			continue
+		}
+
+		_, err = s.db.CreateDownload(ctx, database.CreateDownloadParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			Path:        episodePath,
+			Size:        size,
+			UserID:      user.ID,
+			EnclosureID: episode.EnclosureID,
+		})
+		if err != nil {
+			return err
+		}
+		downloaded++
+	}
+
+	expired, err := s.db.GetExpiredDownloadsForUser(ctx, database.GetExpiredDownloadsForUserParams{
+		UserID: user.ID,
+		Keep:   int64(keep),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, download := range expired {
+		err := os.Remove(download.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		err = s.db.DeleteDownload(ctx, download.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("downloaded %d episodes, removed %d old episodes\n", downloaded, len(expired))
+	return nil
+}
+
+// download saves rawURL to filePath and returns its size. Partial downloads are
+// kept alongside as a .part file and resumed with a range request.
+func (f *fetcher) download(ctx context.Context, rawURL, filePath string) (int64, error) {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return 0, err
+	}
+
+	partPath := filePath + ".part"
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	// the request is cancelled if the server stops sending data
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	idle := time.AfterFunc(f.downloadIdleTimeout, func() {
+		cancel(fmt.Errorf("%w: no data received for %s", errFetchTimeout, f.downloadIdleTimeout))
+	})
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "gator")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	res, err := f.downloadClient.Do(req)
+	if err != nil {
+		return 0, downloadError(ctx, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the partial file already holds the whole episode
+		return offset, finishDownload(file, partPath, filePath)
+	case res.StatusCode >= 200 && res.StatusCode <= 299:
+		// the server ignored the range, start over
+		if err := file.Truncate(0); err != nil {
+			return 0, err
+		}
+		if offset, err = file.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, &statusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	written, err := io.Copy(file, &idleTimeoutReader{reader: res.Body, timer: idle, timeout: f.downloadIdleTimeout})
+	if err != nil {
+		return 0, downloadError(ctx, err)
+	}
+
+	return offset + written, finishDownload(file, partPath, filePath)
+}
+
+// downloadError reports a stalled download as a timeout rather than as the
+// cancellation used to abort it.
+func downloadError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil && errors.Is(cause, errFetchTimeout) {
+		return cause
+	}
+	return classifyFetchError(err)
+}
+
+// idleTimeoutReader pushes back the idle timer every time data arrives.
+type idleTimeoutReader struct {
+	reader  io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func finishDownload(file *os.File, partPath, filePath string) error {
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partPath, filePath)
+}
+
+// episodeFilename names the file after the episode's title, with part of the
+// enclosure id to keep episodes that share a title apart.
+func episodeFilename(episode database.GetLatestEpisodesForUserRow) string {
+	name := sanitizeFilename(episode.PostTitle)
+	return fmt.Sprintf("%s-%s%s", name, episode.EnclosureID.String()[:8], episodeExtension(episode))
+}
+
+func episodeExtension(episode database.GetLatestEpisodesForUserRow) string {
+	if parsed, err := url.Parse(episode.Url); err == nil {
+		ext := path.Ext(parsed.Path)
+		if len(ext) > 1 && len(ext) <= 5 {
+			return ext
+		}
+	}
+
+	if extensions, err := mime.ExtensionsByType(episode.MimeType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ""
+}
+
+func sanitizeFilename(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -_.()", r) {
+			return r
+		}
+		return '_'
+	}, name)
+
+	cleaned = strings.Trim(strings.TrimSpace(cleaned), ".")
+	if runes := []rune(cleaned); len(runes) > 100 {
+		cleaned = string(runes[:100])
+	}
+	if cleaned == "" {
+		return "episode"
+	}
+	return cleaned
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carsondecker/gator/internal/config"
+)
+
+func TestDownload(t *testing.T) {
+	const episode = "the whole episode"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ranged.mp3":
+			var start int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err != nil {
+				w.Write([]byte(episode))
+				return
+			}
+			if start >= len(episode) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(episode)-1, len(episode)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(episode[start:]))
+		case "/unranged.mp3":
+			w.Write([]byte(episode))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newFetcher(config.Config{})
+
+	tests := []struct {
+		name    string
+		path    string
+		partial string
+		wantErr bool
+	}{
+		{name: "fresh download", path: "/ranged.mp3"},
+		{name: "resumed with a range request", path: "/ranged.mp3", partial: "the whole"},
+		{name: "server ignores the range", path: "/unranged.mp3", partial: "garbage"},
+		{name: "partial file is already complete", path: "/ranged.mp3", partial: episode},
+		{name: "missing episode", path: "/missing.mp3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "feed", "episode.mp3")
+			if tt.partial != "" {
+				if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filePath+".part", []byte(tt.partial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			size, err := f.download(context.Background(), server.URL+tt.path, filePath)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("download() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("download() error = %v", err)
+			}
+
+			got, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != episode {
+				t.Errorf("downloaded %q, want %q", got, episode)
+			}
+			if size != int64(len(episode)) {
+				t.Errorf("download() size = %d, want %d", size, len(episode))
+			}
+			if _, err := os.Stat(filePath + ".part"); !os.IsNotExist(err) {
+				t.Errorf("partial file was left behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 10)))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	f := newFetcher(config.Config{FetchTimeoutSeconds: 1})
+
+	filePath := filepath.Join(t.TempDir(), "episode.mp3")
+	if _, err := f.download(context.Background(), server.URL, filePath); !errors.Is(err, errFetchTimeout) {
+		t.Errorf("download() error = %v, want %v", err, errFetchTimeout)
+	}
+	if _, err := os.Stat(filePath + ".part"); err != nil {
+		t.Errorf("partial file should be kept for resuming: %v", err)
+	}
+}
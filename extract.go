@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// minArticleLength is the amount of text below which an extracted article is
+// assumed to be a teaser or an error page rather than the post itself.
+const minArticleLength = 250
+
+var (
+	// unlikelyCandidates match the class and id of page furniture, which is
+	// removed before scoring
+	unlikelyCandidates = regexp.MustCompile(`(?i)comment|sidebar|footer|footnote|masthead|menu|nav|share|social|related|sponsor|promo|banner|cookie|subscribe|newsletter|popup|modal|breadcrumb|disqus|remark`)
+	maybeCandidates    = regexp.MustCompile(`(?i)and|article|body|column|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	negativeWeight     = regexp.MustCompile(`(?i)comment|meta|footer|footnote|sidebar|widget|share|related|ad-|ads|hidden|combx|contact|shoutbox`)
+)
+
+// nonContentElements never contain the main content of a page.
+var nonContentElements = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Header: true,
+	atom.Footer: true,
+	atom.Aside:  true,
+	atom.Button: true,
+	atom.Select: true,
+	atom.Svg:    true,
+}
+
+// fetchArticle downloads a post's web page and extracts the article from it,
+// returning sanitized html with absolute links.
+func (f *fetcher) fetchArticle(ctx context.Context, articleURL string) (string, error) {
+	page, err := f.get(ctx, articleURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if !page.isHTML() {
+		return "", fmt.Errorf("%s is not a web page", articleURL)
+	}
+
+	reader, err := charset.NewReader(bytes.NewReader(page.body), page.contentType)
+	if err != nil {
+		return "", err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", errors.New("could not parse html page")
+	}
+
+	article := extractArticle(doc)
+	if article == nil {
+		return "", fmt.Errorf("could not find an article on %s", articleURL)
+	}
+	absoluteURLs(article, page.url)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, article); err != nil {
+		return "", err
+	}
+	return sanitizeHTML(buf.String()), nil
+}
+
+// extractArticle finds the element holding a page's main content using a
+// simplified version of the readability algorithm: paragraphs are scored by
+// their length and punctuation, scores are propagated to their ancestors and
+// the best scoring ancestor, discounted by its share of link text, wins.
+func extractArticle(doc *html.Node) *html.Node {
+	removeClutter(doc)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = classWeight(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	for node := range doc.Descendants() {
+		if node.Type != html.ElementNode {
+			continue
+		}
+		if node.DataAtom != atom.P && node.DataAtom != atom.Pre && node.DataAtom != atom.Td {
+			continue
+		}
+
+		text := nodeText(node)
+		if len(text) < 25 {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		addScore(node.Parent, score)
+		if node.Parent != nil {
+			addScore(node.Parent.Parent, score/2)
+		}
+	}
+
+	var best *html.Node
+	var bestScore float64
+	for _, candidate := range candidates {
+		score := scores[candidate] * (1 - linkDensity(candidate))
+		if best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+
+	if best == nil || len(nodeText(best)) < minArticleLength {
+		return nil
+	}
+	return best
+}
+
+// removeClutter drops scripts, navigation and other elements that are
+// unlikely to be part of the article.
+func removeClutter(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode || (child.Type == html.ElementNode && isClutter(child)) {
+			node.RemoveChild(child)
+		} else {
+			removeClutter(child)
+		}
+		child = next
+	}
+}
+
+func isClutter(node *html.Node) bool {
+	if strippedElements[node.DataAtom] || nonContentElements[node.DataAtom] {
+		return true
+	}
+	if node.DataAtom == atom.Body || node.DataAtom == atom.Article || node.DataAtom == atom.Main {
+		return false
+	}
+
+	match := attr(node, "class") + " " + attr(node, "id")
+	return unlikelyCandidates.MatchString(match) && !maybeCandidates.MatchString(match)
+}
+
+func classWeight(node *html.Node) float64 {
+	var weight float64
+	switch node.DataAtom {
+	case atom.Article, atom.Main:
+		weight += 25
+	case atom.Div:
+		weight += 5
+	case atom.Blockquote, atom.Pre, atom.Td:
+		weight += 3
+	case atom.Form, atom.Ul, atom.Ol, atom.Dl, atom.Li:
+		weight -= 3
+	}
+
+	for _, value := range []string{attr(node, "class"), attr(node, "id")} {
+		if value == "" {
+			continue
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that is inside links.
+func linkDensity(node *html.Node) float64 {
+	textLength := len(nodeText(node))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	for descendant := range node.Descendants() {
+		if descendant.Type == html.ElementNode && descendant.DataAtom == atom.A {
+			linkLength += len(nodeText(descendant))
+		}
+	}
+	return min(float64(linkLength)/float64(textLength), 1)
+}
+
+// nodeText returns the text of a node and its descendants with whitespace
+// collapsed.
+func nodeText(node *html.Node) string {
+	var text strings.Builder
+	for descendant := range node.Descendants() {
+		if descendant.Type == html.TextNode {
+			text.WriteString(descendant.Data)
+			text.WriteString(" ")
+		}
+	}
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// absoluteURLs rewrites relative links and image sources so the article still
+// works once it's been separated from its page.
+func absoluteURLs(node *html.Node, base *url.URL) {
+	for descendant := range node.Descendants() {
+		if descendant.Type != html.ElementNode {
+			continue
+		}
+		for i, a := range descendant.Attr {
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			if resolved, err := base.Parse(strings.TrimSpace(a.Val)); err == nil {
+				descendant.Attr[i].Val = resolved.String()
+			}
+		}
+	}
+}
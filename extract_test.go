@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carsondecker/gator/internal/config"
+	"golang.org/x/net/html"
+)
+
+func readArticleFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "articles", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestExtractArticle(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:    "blog post with navigation, sidebar and comments",
+			fixture: "blog.html",
+			contains: []string{
+				"Planting a winter garden",
+				"Winter gardens are easier than they look",
+				"Start with hardy greens",
+				"Mulch generously",
+			},
+			excludes: []string{
+				"Archive",
+				"Subscribe to our newsletter",
+				"Great post, thanks for sharing",
+				"Copyright 2026",
+				"window.analytics",
+			},
+		},
+		{
+			name:     "prose is preferred over a list of links",
+			fixture:  "links.html",
+			contains: []string{"This is the real article text", "A second paragraph adds more prose"},
+			excludes: []string{"A link list entry"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(bytes.NewReader(readArticleFixture(t, tt.fixture)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			article := extractArticle(doc)
+			if article == nil {
+				t.Fatal("extractArticle() found no article")
+			}
+			text := nodeText(article)
+			for _, want := range tt.contains {
+				if !strings.Contains(text, want) {
+					t.Errorf("article is missing %q, got:\n%s", want, text)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(text, unwanted) {
+					t.Errorf("article contains %q, got:\n%s", unwanted, text)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractArticleRejectsTeasers(t *testing.T) {
+	doc, err := html.Parse(bytes.NewReader(readArticleFixture(t, "teaser.html")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if article := extractArticle(doc); article != nil {
+		t.Errorf("extractArticle() = %q, want no article", nodeText(article))
+	}
+}
+
+func TestFetchArticle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2026/winter-garden":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(readArticleFixture(t, "blog.html"))
+		case "/feed.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write(readFixture(t, "rss.xml"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newFetcher(config.Config{})
+
+	article, err := f.fetchArticle(context.Background(), server.URL+"/2026/winter-garden")
+	if err != nil {
+		t.Fatalf("fetchArticle() error = %v", err)
+	}
+	for _, want := range []string{
+		`href="` + server.URL + `/guides/greens"`,
+		`src="` + server.URL + `/images/frame.jpg"`,
+	} {
+		if !strings.Contains(article, want) {
+			t.Errorf("article is missing absolute url %s, got:\n%s", want, article)
+		}
+	}
+	if strings.Contains(article, "tracker.example") {
+		t.Errorf("article contains a tracking pixel:\n%s", article)
+	}
+
+	if _, err := f.fetchArticle(context.Background(), server.URL+"/feed.xml"); err == nil {
+		t.Error("fetchArticle() of a feed succeeded, want an error")
+	}
+	if _, err := f.fetchArticle(context.Background(), server.URL+"/missing"); err == nil {
+		t.Error("fetchArticle() of a missing page succeeded, want an error")
+	}
+}
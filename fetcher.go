@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/carsondecker/gator/internal/config"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultFetchTimeout   = 30 * time.Second
+	defaultMaxBodySize    = 10 << 20
+)
+
+var (
+	errFetchTimeout = errors.New("fetch timed out")
+	errBodyTooLarge = errors.New("response body too large")
+)
+
+// statusError is returned for responses with a non-2xx status code.
+type statusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the server asked us to wait before trying again
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return "unexpected status " + e.Status
+}
+
+// statusClass describes what a failing status code means for a feed.
+func statusClass(statusCode int) string {
+	switch {
+	case statusCode == http.StatusGone:
+		return "gone"
+	case statusCode == http.StatusTooManyRequests:
+		return "rate limited"
+	case statusCode >= 500:
+		return "server error"
+	case statusCode >= 400:
+		return "client error"
+	default:
+		return "unexpected status"
+	}
+}
+
+// fetcher makes the http requests for feeds and the pages they're discovered
+// from, bounding how long a request may take and how much it may download.
+type fetcher struct {
+	client      *http.Client
+	maxBodySize int64
+	// downloadClient has no overall timeout since media files can take
+	// arbitrarily long to download. Instead the server has to start responding
+	// within the fetch timeout and downloads stall out once no data arrives
+	// for downloadIdleTimeout.
+	downloadClient      *http.Client
+	downloadIdleTimeout time.Duration
+}
+
+func newFetcher(cfg config.Config) *fetcher {
+	connectTimeout := defaultConnectTimeout
+	if cfg.FetchConnectTimeoutSeconds > 0 {
+		connectTimeout = time.Duration(cfg.FetchConnectTimeoutSeconds) * time.Second
+	}
+	fetchTimeout := defaultFetchTimeout
+	if cfg.FetchTimeoutSeconds > 0 {
+		fetchTimeout = time.Duration(cfg.FetchTimeoutSeconds) * time.Second
+	}
+	maxBodySize := int64(defaultMaxBodySize)
+	if cfg.FetchMaxBodyBytes > 0 {
+		maxBodySize = cfg.FetchMaxBodyBytes
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	downloadTransport := transport.Clone()
+	downloadTransport.ResponseHeaderTimeout = fetchTimeout
+
+	return &fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   fetchTimeout,
+		},
+		maxBodySize: maxBodySize,
+		downloadClient: &http.Client{
+			Transport: downloadTransport,
+		},
+		downloadIdleTimeout: fetchTimeout,
+	}
+}
+
+type response struct {
+	// url is where the request ended up after following redirects
+	url         *url.URL
+	movedTo     string
+	statusCode  int
+	header      http.Header
+	contentType string
+	body        []byte
+}
+
+// get requests rawURL and reads the response body. A 304 Not Modified is
+// returned as a response without a body, any other non-2xx status as a
+// *statusError.
+func (f *fetcher) get(ctx context.Context, rawURL string, header http.Header) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, classifyFetchError(err)
+	}
+	defer res.Body.Close()
+
+	result := &response{
+		url:         res.Request.URL,
+		movedTo:     permanentRedirectTarget(res),
+		statusCode:  res.StatusCode,
+		header:      res.Header,
+		contentType: res.Header.Get("Content-Type"),
+	}
+
+	if res.StatusCode == http.StatusNotModified {
+		return result, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &statusError{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+
+	if res.ContentLength > f.maxBodySize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds limit of %d", errBodyTooLarge, res.ContentLength, f.maxBodySize)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, f.maxBodySize+1))
+	if err != nil {
+		return nil, classifyFetchError(err)
+	}
+	if int64(len(body)) > f.maxBodySize {
+		return nil, fmt.Errorf("%w: exceeds limit of %d bytes", errBodyTooLarge, f.maxBodySize)
+	}
+	result.body = body
+
+	return result, nil
+}
+
+func classifyFetchError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %w", errFetchTimeout, err)
+	}
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// http date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// permanentRedirectTarget returns the url reached through a chain of
+// redirects that were all permanent. A temporary redirect anywhere in the
+// chain means the resource may move back, so nothing counts as moved.
+func permanentRedirectTarget(res *http.Response) string {
+	if res.Request.Response == nil {
+		return ""
+	}
+	for req := res.Request; req.Response != nil; req = req.Response.Request {
+		code := req.Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			return ""
+		}
+	}
+	return res.Request.URL.String()
+}
+
+func (r *response) isHTML() bool {
+	contentType := r.contentType
+	if contentType == "" {
+		contentType = http.DetectContentType(r.body)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
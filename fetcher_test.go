@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/carsondecker/gator/internal/config"
+)
+
+func TestPermanentRedirectTarget(t *testing.T) {
+	mux := http.NewServeMux()
+	redirect := func(from, to string, code int) {
+		mux.HandleFunc(from, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, to, code)
+		})
+	}
+	redirect("/moved", "/feed", http.StatusMovedPermanently)
+	redirect("/moved-then-found", "/found", http.StatusMovedPermanently)
+	redirect("/found", "/feed", http.StatusFound)
+	redirect("/found-then-moved", "/moved", http.StatusFound)
+	redirect("/permanent-then-moved", "/moved", http.StatusPermanentRedirect)
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(readFixture(t, "rss.xml"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := newFetcher(config.Config{})
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"no redirect", "/feed", ""},
+		{"301 to 200", "/moved", server.URL + "/feed"},
+		{"301 then 302 to 200", "/moved-then-found", ""},
+		{"302 then 301 to 200", "/found-then-moved", ""},
+		{"308 then 301 to 200", "/permanent-then-moved", server.URL + "/feed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := f.get(context.Background(), server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("get() error = %v", err)
+			}
+			if res.url.String() != server.URL+"/feed" {
+				t.Errorf("get() ended at %s, want %s", res.url, server.URL+"/feed")
+			}
+			if res.movedTo != tt.want {
+				t.Errorf("get() movedTo = %q, want %q", res.movedTo, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBodyLimit(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 2048)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/declared":
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write(body)
+		case "/chunked":
+			// flushing before writing the body leaves out the Content-Length
+			w.(http.Flusher).Flush()
+			w.Write(body)
+		case "/small":
+			w.Write(body[:1024])
+		}
+	}))
+	defer server.Close()
+
+	f := newFetcher(config.Config{FetchMaxBodyBytes: 1024})
+
+	for _, path := range []string{"/declared", "/chunked"} {
+		if _, err := f.get(context.Background(), server.URL+path, nil); !errors.Is(err, errBodyTooLarge) {
+			t.Errorf("get(%s) error = %v, want %v", path, err, errBodyTooLarge)
+		}
+	}
+
+	res, err := f.get(context.Background(), server.URL+"/small", nil)
+	if err != nil {
+		t.Fatalf("get(/small) error = %v", err)
+	}
+	if len(res.body) != 1024 {
+		t.Errorf("get(/small) read %d bytes, want 1024", len(res.body))
+	}
+}
+
+func TestGetTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	f := newFetcher(config.Config{FetchTimeoutSeconds: 1})
+
+	if _, err := f.get(context.Background(), server.URL, nil); !errors.Is(err, errFetchTimeout) {
+		t.Errorf("get() error = %v, want %v", err, errFetchTimeout)
+	}
+}
+
+func TestGetStatusErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/rate-limited":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/unavailable":
+			w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/server-error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newFetcher(config.Config{})
+
+	tests := []struct {
+		path           string
+		wantStatusCode int
+		wantClass      string
+		minRetryAfter  time.Duration
+		maxRetryAfter  time.Duration
+	}{
+		{"/gone", http.StatusGone, "gone", 0, 0},
+		{"/rate-limited", http.StatusTooManyRequests, "rate limited", 2 * time.Minute, 2 * time.Minute},
+		{"/unavailable", http.StatusServiceUnavailable, "server error", 59 * time.Minute, time.Hour},
+		{"/server-error", http.StatusInternalServerError, "server error", 0, 0},
+		{"/missing", http.StatusNotFound, "client error", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := f.get(context.Background(), server.URL+tt.path, nil)
+
+			var statusErr *statusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("get() error = %v, want a *statusError", err)
+			}
+			if statusErr.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, tt.wantStatusCode)
+			}
+			if class := statusClass(statusErr.StatusCode); class != tt.wantClass {
+				t.Errorf("statusClass() = %q, want %q", class, tt.wantClass)
+			}
+			if statusErr.RetryAfter < tt.minRetryAfter || statusErr.RetryAfter > tt.maxRetryAfter {
+				t.Errorf("RetryAfter = %v, want between %v and %v", statusErr.RetryAfter, tt.minRetryAfter, tt.maxRetryAfter)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"missing", "", 0, 0},
+		{"seconds", "90", 90 * time.Second, 90 * time.Second},
+		{"zero seconds", "0", 0, 0},
+		{"negative seconds", "-5", 0, 0},
+		{"http date", time.Now().Add(10 * time.Minute).UTC().Format(http.TimeFormat), 9 * time.Minute, 10 * time.Minute},
+		{"http date in the past", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+		{"garbage", "later", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestFetchFeedNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v2"`)
+		w.Write(readFixture(t, "rss.xml"))
+	}))
+	defer server.Close()
+
+	f := newFetcher(config.Config{})
+	validators := cacheValidators{etag: `"v1"`, lastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}
+
+	result, err := f.fetchFeed(context.Background(), server.URL, validators)
+	if err != nil {
+		t.Fatalf("fetchFeed() error = %v", err)
+	}
+	if result.feed != nil {
+		t.Error("fetchFeed() returned a feed for a 304 response")
+	}
+	if result.validators != validators {
+		t.Errorf("validators = %+v, want %+v", result.validators, validators)
+	}
+
+	result, err = f.fetchFeed(context.Background(), server.URL, cacheValidators{})
+	if err != nil {
+		t.Fatalf("fetchFeed() error = %v", err)
+	}
+	if result.feed == nil {
+		t.Fatal("fetchFeed() returned no feed for a 200 response")
+	}
+	if want := (cacheValidators{etag: `"v2"`}); result.validators != want {
+		t.Errorf("validators = %+v, want %+v", result.validators, want)
+	}
+}
@@ -5,4 +5,7 @@ go 1.23.4
 require (
 	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.38.0
 )
+
+require golang.org/x/text v0.23.0 // indirect
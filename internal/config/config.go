@@ -8,6 +8,13 @@ import (
 type Config struct {
 	DbURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+
+	FetchConnectTimeoutSeconds int   `json:"fetch_connect_timeout_seconds,omitempty"`
+	FetchTimeoutSeconds        int   `json:"fetch_timeout_seconds,omitempty"`
+	FetchMaxBodyBytes          int64 `json:"fetch_max_body_bytes,omitempty"`
+
+	DownloadDir          string `json:"download_dir,omitempty"`
+	DownloadKeepEpisodes int    `json:"download_keep_episodes,omitempty"`
 }
 
 const configFileName = ".gatorconfig.json"
@@ -41,6 +48,19 @@ func (c Config) SetUser(name string) error {
 	return nil
 }
 
+func (c Config) GetDownloadDir() (string, error) {
+	if c.DownloadDir != "" {
+		return c.DownloadDir, nil
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return homePath + "/gator/downloads", nil
+}
+
 func getConfigFilePath() (string, error) {
 	homePath, err := os.UserHomeDir()
 	if err != nil {
@@ -122,6 +122,27 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	return items, nil
 }
 
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1, updated_at = CURRENT_TIMESTAMP
+WHERE feed_id = $2
+    AND user_id NOT IN (
+        SELECT user_id
+        FROM feed_follows
+        WHERE feed_id = $1
+    )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
 const unfollowFeedForUser = `-- name: UnfollowFeedForUser :exec
 DELETE FROM feed_follows ff
 WHERE user_id = $1 AND feed_id = $2
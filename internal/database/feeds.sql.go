@@ -7,31 +7,41 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, title, description, site_url, language)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_fetch_at, ttl_minutes, skip_hours, skip_days, title, description, site_url, language, last_status_code, disabled_at, full_article
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	Title       string
+	Description string
+	SiteUrl     string
+	Language    string
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -42,6 +52,10 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
 	)
 	var i Feed
 	err := row.Scan(
@@ -52,12 +66,70 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.LastStatusCode,
+		&i.DisabledAt,
+		&i.FullArticle,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = CURRENT_TIMESTAMP, last_error = $2, last_status_code = $3
+WHERE id = $1
+`
+
+type DisableFeedParams struct {
+	ID             uuid.UUID
+	LastError      sql.NullString
+	LastStatusCode sql.NullInt32
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.ID, arg.LastError, arg.LastStatusCode)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    consecutive_failures = 0,
+    last_error = NULL,
+    last_status_code = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_fetch_at, ttl_minutes, skip_hours, skip_days, title, description, site_url, language, last_status_code, disabled_at, full_article
 FROM feeds
 WHERE url = $1
 `
@@ -73,12 +145,27 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.LastStatusCode,
+		&i.DisabledAt,
+		&i.FullArticle,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_fetch_at, ttl_minutes, skip_hours, skip_days, title, description, site_url, language, last_status_code, disabled_at, full_article FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -98,6 +185,21 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.LastStatusCode,
+			&i.DisabledAt,
+			&i.FullArticle,
 		); err != nil {
 			return nil, err
 		}
@@ -113,17 +215,27 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getFeedsWithUser = `-- name: GetFeedsWithUser :many
-SELECT f.id, f.name, f.url, u.name AS user_name
+SELECT f.id, f.name, f.url, f.title, f.description, f.site_url, f.language, f.consecutive_failures, f.last_error, f.next_fetch_at, f.last_status_code, f.disabled_at, f.full_article, u.name AS user_name
 FROM feeds f
     JOIN users u
         ON f.user_id = u.id
 `
 
 type GetFeedsWithUserRow struct {
-	ID       uuid.UUID
-	Name     string
-	Url      string
-	UserName string
+	ID                  uuid.UUID
+	Name                string
+	Url                 string
+	Title               string
+	Description         string
+	SiteUrl             string
+	Language            string
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	NextFetchAt         sql.NullTime
+	LastStatusCode      sql.NullInt32
+	DisabledAt          sql.NullTime
+	FullArticle         bool
+	UserName            string
 }
 
 func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow, error) {
@@ -139,6 +251,16 @@ func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow,
 			&i.ID,
 			&i.Name,
 			&i.Url,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.NextFetchAt,
+			&i.LastStatusCode,
+			&i.DisabledAt,
+			&i.FullArticle,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -155,14 +277,27 @@ func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow,
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1
+UPDATE feeds
+SET updated_at = CURRENT_TIMESTAMP, last_fetched_at = CURRENT_TIMESTAMP,
+    next_fetch_at = CURRENT_TIMESTAMP + $1::INTEGER * INTERVAL '1 second'
+WHERE id = (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+        AND (next_fetch_at IS NULL OR next_fetch_at <= CURRENT_TIMESTAMP)
+        AND (ttl_minutes IS NULL OR last_fetched_at IS NULL
+            OR last_fetched_at + ttl_minutes * INTERVAL '1 minute' <= CURRENT_TIMESTAMP)
+        AND NOT EXTRACT(HOUR FROM CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::INTEGER = ANY(skip_hours)
+        AND NOT TO_CHAR(CURRENT_TIMESTAMP AT TIME ZONE 'UTC', 'FMDay') = ANY(skip_days)
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, next_fetch_at, ttl_minutes, skip_hours, skip_days, title, description, site_url, language, last_status_code, disabled_at, full_article
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetNextFeedToFetch(ctx context.Context, leaseSeconds int32) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch, leaseSeconds)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -172,17 +307,141 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.LastStatusCode,
+		&i.DisabledAt,
+		&i.FullArticle,
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = $1,
+    last_status_code = $2,
+    next_fetch_at = CURRENT_TIMESTAMP + GREATEST(
+        INTERVAL '1 minute' * POWER(2, LEAST(consecutive_failures, 10)),
+        $3::INTEGER * INTERVAL '1 second'
+    )
+WHERE id = $4
+`
+
+type RecordFeedFailureParams struct {
+	LastError         sql.NullString
+	LastStatusCode    sql.NullInt32
+	RetryAfterSeconds int32
+	ID                uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.LastStatusCode,
+		arg.RetryAfterSeconds,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_status_code = NULL,
+    next_fetch_at = CURRENT_TIMESTAMP + $1::INTEGER * INTERVAL '1 second'
+WHERE id = $2
+`
+
+type RecordFeedSuccessParams struct {
+	PollIntervalSeconds int32
+	ID                  uuid.UUID
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.PollIntervalSeconds, arg.ID)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
 UPDATE feeds
-SET updated_at = CURRENT_TIMESTAMP, last_fetched_at = CURRENT_TIMESTAMP
+SET etag = $2, last_modified = $3
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type SetFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedFullArticle = `-- name: SetFeedFullArticle :exec
+UPDATE feeds
+SET full_article = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetFeedFullArticleParams struct {
+	ID          uuid.UUID
+	FullArticle bool
+}
+
+func (q *Queries) SetFeedFullArticle(ctx context.Context, arg SetFeedFullArticleParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFullArticle, arg.ID, arg.FullArticle)
+	return err
+}
+
+const setFeedSchedulingHints = `-- name: SetFeedSchedulingHints :exec
+UPDATE feeds
+SET ttl_minutes = $2, skip_hours = $3, skip_days = $4
+WHERE id = $1
+`
+
+type SetFeedSchedulingHintsParams struct {
+	ID         uuid.UUID
+	TtlMinutes sql.NullInt32
+	SkipHours  []int32
+	SkipDays   []string
+}
+
+func (q *Queries) SetFeedSchedulingHints(ctx context.Context, arg SetFeedSchedulingHintsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSchedulingHints,
+		arg.ID,
+		arg.TtlMinutes,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+	)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url)
 	return err
 }
@@ -11,14 +11,49 @@ import (
 	"github.com/google/uuid"
 )
 
+type Download struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Path        string
+	Size        int64
+	UserID      uuid.UUID
+	EnclosureID uuid.UUID
+}
+
+type Enclosure struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Url       string
+	MimeType  string
+	Length    int64
+	PostID    uuid.UUID
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	NextFetchAt         sql.NullTime
+	TtlMinutes          sql.NullInt32
+	SkipHours           []int32
+	SkipDays            []string
+	Title               string
+	Description         string
+	SiteUrl             string
+	Language            string
+	LastStatusCode      sql.NullInt32
+	DisabledAt          sql.NullTime
+	FullArticle         bool
 }
 
 type FeedFollow struct {
@@ -30,14 +65,26 @@ type FeedFollow struct {
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          string
+	PublishedAt          time.Time
+	FeedID               uuid.UUID
+	Guid                 string
+	ContentHash          string
+	Revisions            int32
+	Content              string
+	Author               string
+	PublishedAtEstimated bool
+	Article              string
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
 }
 
 type User struct {
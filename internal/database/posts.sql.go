@@ -12,43 +12,65 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const adoptLegacyPostGuid = `-- name: AdoptLegacyPostGuid :exec
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+    AND url = $3
+    AND guid = url
+    AND NOT EXISTS (
+        SELECT 1
+        FROM posts existing
+        WHERE existing.feed_id = $2 AND existing.guid = $1
+    )
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+type AdoptLegacyPostGuidParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
+func (q *Queries) AdoptLegacyPostGuid(ctx context.Context, arg AdoptLegacyPostGuidParams) error {
+	_, err := q.db.ExecContext(ctx, adoptLegacyPostGuid, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
+
+const getFeedPostingInterval = `-- name: GetFeedPostingInterval :one
+SELECT CAST(COALESCE(EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)) / NULLIF(COUNT(*) - 1, 0), 0) AS FLOAT8) AS average_interval_seconds
+FROM (
+    SELECT published_at
+    FROM posts
+    WHERE feed_id = $1 AND NOT published_at_estimated
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent_posts
+`
+
+func (q *Queries) GetFeedPostingInterval(ctx context.Context, feedID uuid.UUID) (float64, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostingInterval, feedID)
+	var average_interval_seconds float64
+	err := row.Scan(&average_interval_seconds)
+	return average_interval_seconds, err
+}
+
+const getPostForUserByUrl = `-- name: GetPostForUserByUrl :one
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.guid, p.content_hash, p.revisions, p.content, p.author, p.published_at_estimated, p.article
+FROM posts p
+    JOIN feed_follows ff
+        ON ff.feed_id = p.feed_id
+WHERE ff.user_id = $1 AND p.url = $2
+ORDER BY p.published_at DESC
+LIMIT 1
+`
+
+type GetPostForUserByUrlParams struct {
+	UserID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) GetPostForUserByUrl(ctx context.Context, arg GetPostForUserByUrlParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUserByUrl, arg.UserID, arg.Url)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -59,26 +81,47 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revisions,
+		&i.Content,
+		&i.Author,
+		&i.PublishedAtEstimated,
+		&i.Article,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id
+SELECT p.id, p.created_at, p.updated_at, p.title, p.url, p.description, p.published_at, p.feed_id, p.guid, p.content_hash, p.revisions, p.content, p.author, p.published_at_estimated, p.article
 FROM feed_follows ff
     JOIN posts p
         ON ff.feed_id = p.feed_id
-WHERE user_id = $1
-LIMIT $2
+WHERE ff.user_id = $1
+    AND ($2::TEXT = '' OR p.author ILIKE '%' || $2::TEXT || '%')
+    AND ($3::TEXT = '' OR EXISTS (
+        SELECT 1
+        FROM post_categories pc
+        WHERE pc.post_id = p.id AND LOWER(pc.name) = LOWER($3::TEXT)
+    ))
+ORDER BY p.published_at DESC
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID    uuid.UUID
+	Author    string
+	Category  string
+	PostLimit int32
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Author,
+		arg.Category,
+		arg.PostLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +138,13 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Revisions,
+			&i.Content,
+			&i.Author,
+			&i.PublishedAtEstimated,
+			&i.Article,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +158,117 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+    AND guid NOT IN (
+        SELECT guid
+        FROM posts
+        WHERE feed_id = $1
+    )
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const setPostArticle = `-- name: SetPostArticle :exec
+UPDATE posts
+SET article = $2
+WHERE id = $1
+`
+
+type SetPostArticleParams struct {
+	ID      uuid.UUID
+	Article string
+}
+
+func (q *Queries) SetPostArticle(ctx context.Context, arg SetPostArticleParams) error {
+	_, err := q.db.ExecContext(ctx, setPostArticle, arg.ID, arg.Article)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, published_at_estimated)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    content_hash = EXCLUDED.content_hash,
+    updated_at = EXCLUDED.updated_at,
+    revisions = posts.revisions + CASE
+        WHEN posts.content_hash = '' THEN 0
+        WHEN posts.content = '' AND posts.title = EXCLUDED.title AND posts.description = EXCLUDED.description THEN 0
+        ELSE 1
+    END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id, revisions, (xmax = 0) AS inserted
+`
+
+type UpsertPostParams struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          string
+	PublishedAt          time.Time
+	FeedID               uuid.UUID
+	Guid                 string
+	ContentHash          string
+	Content              string
+	Author               string
+	PublishedAtEstimated bool
+}
+
+type UpsertPostRow struct {
+	ID        uuid.UUID
+	Revisions int32
+	Inserted  bool
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (UpsertPostRow, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.ContentHash,
+		arg.Content,
+		arg.Author,
+		arg.PublishedAtEstimated,
+	)
+	var i UpsertPostRow
+	err := row.Scan(&i.ID, &i.Revisions, &i.Inserted)
+	return i, err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	Description string           `json:"description"`
+	Language    string           `json:"language"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Author      *JSONFeedAuthor  `json:"author"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            jsonFeedID `json:"id"`
+	URL           string     `json:"url"`
+	ExternalURL   string     `json:"external_url"`
+	Title         string     `json:"title"`
+	ContentHTML   string     `json:"content_html"`
+	ContentText   string     `json:"content_text"`
+	Summary       string     `json:"summary"`
+	DatePublished string     `json:"date_published"`
+	DateModified  string     `json:"date_modified"`
+
+	Authors []JSONFeedAuthor `json:"authors"`
+	Author  *JSONFeedAuthor  `json:"author"`
+	Tags    []string         `json:"tags"`
+
+	Attachments []JSONFeedAttachment `json:"attachments"`
+}
+
+// jsonFeedID is an item id. The spec requires ids to be strings but tells
+// readers to coerce other values, such as numbers, to strings.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		*id = ""
+		return nil
+	}
+	*id = jsonFeedID(strings.TrimSpace(string(data)))
+	return nil
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type JSONFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+}
+
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	var jsonFeed JSONFeed
+	if err := json.Unmarshal(body, &jsonFeed); err != nil {
+		return nil, err
+	}
+	// any json document would decode, so require the version every feed must
+	// declare to avoid mistaking api responses for feeds
+	if !strings.HasPrefix(jsonFeed.Version, jsonFeedVersionPrefix) {
+		return nil, fmt.Errorf("json document is not a JSON Feed, version %q is not %s...", jsonFeed.Version, jsonFeedVersionPrefix)
+	}
+
+	return jsonFeed.toRSSFeed(), nil
+}
+
+func (f JSONFeed) toRSSFeed() *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Title
+	feed.Channel.Link = f.HomePageURL
+	feed.Channel.Description = f.Description
+	feed.Channel.Language = f.Language
+
+	for _, jsonItem := range f.Items {
+		item := RSSItem{
+			Guid:        string(jsonItem.ID),
+			Title:       jsonItem.Title,
+			Link:        firstNonEmpty(jsonItem.URL, jsonItem.ExternalURL),
+			Description: firstNonEmpty(jsonItem.Summary, jsonItem.ContentHTML, jsonItem.ContentText),
+			PubDate:     firstNonEmpty(jsonItem.DatePublished, jsonItem.DateModified),
+			Content:     firstNonEmpty(jsonItem.ContentHTML, jsonItem.ContentText),
+			Author:      firstNonEmpty(authorNames(jsonItem.Authors, jsonItem.Author), authorNames(f.Authors, f.Author)),
+			Categories:  jsonItem.Tags,
+		}
+		for _, attachment := range jsonItem.Attachments {
+			item.Enclosures = append(item.Enclosures, RSSEnclosure{
+				URL:    attachment.URL,
+				Length: strconv.FormatInt(attachment.SizeInBytes, 10),
+				Type:   attachment.MimeType,
+			})
+		}
+		// ids are only required to be unique strings, but are usually permalinks
+		if item.Link == "" && strings.HasPrefix(item.Guid, "http") {
+			item.Link = item.Guid
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return &feed
+}
+
+// authorNames joins the names of a version 1.1 authors list, falling back to
+// the single author object used by version 1.0.
+func authorNames(authors []JSONFeedAuthor, author *JSONFeedAuthor) string {
+	if len(authors) == 0 && author != nil {
+		authors = []JSONFeedAuthor{*author}
+	}
+	var names []string
+	for _, author := range authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func isJSONFeed(contentType string, body []byte) bool {
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(string(body)), "{")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/carsondecker/gator/internal/config"
 	"github.com/carsondecker/gator/internal/database"
@@ -13,8 +16,10 @@ import (
 )
 
 type state struct {
-	db     *database.Queries
-	config *config.Config
+	db      *database.Queries
+	sqlDB   *sql.DB
+	config  *config.Config
+	fetcher *fetcher
 }
 
 func main() {
@@ -27,8 +32,10 @@ func main() {
 	dbQueries := database.New(db)
 
 	state := &state{
-		db:     dbQueries,
-		config: &cfg,
+		db:      dbQueries,
+		sqlDB:   db,
+		config:  &cfg,
+		fetcher: newFetcher(cfg),
 	}
 
 	commands, err := initCommands()
@@ -44,7 +51,10 @@ func main() {
 		args: args[2:],
 	}
 
-	err = commands.run(state, command)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = commands.run(ctx, state, command)
 	handleError(err)
 }
 
@@ -0,0 +1,47 @@
+package main
+
+// RDFFeed is an RSS 1.0 document, where items are siblings of the channel
+// rather than children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+	} `xml:"channel"`
+	Items []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+func (f RDFFeed) toRSSFeed() *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = f.Channel.Title
+	feed.Channel.Link = f.Channel.Link
+	feed.Channel.Description = f.Channel.Description
+	feed.Channel.Language = f.Channel.Language
+
+	for _, rdfItem := range f.Items {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Guid:        rdfItem.About,
+			Title:       rdfItem.Title,
+			Link:        rdfItem.Link,
+			Description: rdfItem.Description,
+			PubDate:     rdfItem.Date,
+			Content:     rdfItem.Content,
+			Creator:     rdfItem.Creator,
+			Categories:  rdfItem.Subjects,
+		})
+	}
+
+	return &feed
+}
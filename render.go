@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const defaultRenderWidth = 80
+
+// blockElements start a new paragraph in the rendered text.
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Aside:      true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Li:         true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Pre:        true,
+	atom.Blockquote: true,
+	atom.Hr:         true,
+}
+
+// textRenderer turns html into plain text paragraphs wrapped to a width, with
+// links replaced by numbered references listed after the text.
+type textRenderer struct {
+	width int
+	base  *url.URL
+
+	paragraphs []paragraph
+	line       strings.Builder
+	// prefix is written before every line of the current paragraph, bullet
+	// before its first line only
+	prefix string
+	bullet string
+	pre    bool
+
+	links []string
+}
+
+type paragraph struct {
+	text     string
+	listItem bool
+}
+
+// renderHTML converts html to plain text wrapped to width. Relative links are
+// resolved against baseURL.
+func renderHTML(content, baseURL string, width int) string {
+	base, _ := url.Parse(baseURL)
+	r := &textRenderer{
+		width: width,
+		base:  base,
+	}
+
+	parent := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(content), parent)
+	if err != nil {
+		return content
+	}
+	for _, node := range nodes {
+		r.render(node)
+	}
+	r.flush()
+
+	var text strings.Builder
+	for i, p := range r.paragraphs {
+		// list items are kept together instead of separated by a blank line
+		if i > 0 && p.listItem && r.paragraphs[i-1].listItem {
+			text.WriteString("\n")
+		} else if i > 0 {
+			text.WriteString("\n\n")
+		}
+		text.WriteString(p.text)
+	}
+	if len(r.links) > 0 {
+		text.WriteString("\n\nLinks:")
+		for i, link := range r.links {
+			fmt.Fprintf(&text, "\n[%d] %s", i+1, link)
+		}
+	}
+	return text.String()
+}
+
+func (r *textRenderer) render(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		r.text(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if strippedElements[node.DataAtom] {
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		r.line.WriteString("\n")
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(node, "alt")); alt != "" && !isTrackingPixel(node) {
+			r.text("[" + alt + "]")
+		}
+		return
+	case atom.Hr:
+		r.flush()
+		r.paragraphs = append(r.paragraphs, paragraph{text: strings.Repeat("-", min(r.width, 20))})
+		return
+	}
+
+	if blockElements[node.DataAtom] {
+		r.flush()
+	}
+
+	prefix, bullet, pre := r.prefix, r.bullet, r.pre
+	switch node.DataAtom {
+	case atom.Blockquote:
+		r.prefix += "> "
+	case atom.Li:
+		// nested lists line up with the text of the enclosing item
+		r.prefix += strings.Repeat(" ", len(r.bullet))
+		r.bullet = listBullet(node)
+	case atom.Pre:
+		r.pre = true
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.render(child)
+	}
+
+	if node.DataAtom == atom.A {
+		if ref := r.link(attr(node, "href")); ref != 0 {
+			fmt.Fprintf(&r.line, "[%d]", ref)
+		}
+	}
+
+	if blockElements[node.DataAtom] {
+		r.flush()
+	}
+	r.prefix, r.bullet, r.pre = prefix, bullet, pre
+}
+
+// text appends text to the current paragraph, collapsing whitespace outside
+// of preformatted blocks.
+func (r *textRenderer) text(text string) {
+	if r.pre {
+		r.line.WriteString(text)
+		return
+	}
+
+	collapsed := strings.Join(strings.Fields(text), " ")
+	current := r.line.String()
+	atLineStart := current == "" || strings.HasSuffix(current, "\n") || strings.HasSuffix(current, " ")
+	if text != "" && isSpace(text[0]) && !atLineStart {
+		r.line.WriteString(" ")
+	}
+	r.line.WriteString(collapsed)
+	if collapsed != "" && isSpace(text[len(text)-1]) {
+		r.line.WriteString(" ")
+	}
+}
+
+// link records a link and returns its reference number, or 0 for links that
+// aren't worth listing.
+func (r *textRenderer) link(href string) int {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || !isSafeURL(href) {
+		return 0
+	}
+	if r.base != nil {
+		if resolved, err := r.base.Parse(href); err == nil {
+			href = resolved.String()
+		}
+	}
+
+	for i, link := range r.links {
+		if link == href {
+			return i + 1
+		}
+	}
+	r.links = append(r.links, href)
+	return len(r.links)
+}
+
+// flush ends the current paragraph, wrapping its lines to the width.
+func (r *textRenderer) flush() {
+	text := r.line.String()
+	r.line.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	var lines []string
+	if r.pre {
+		lines = strings.Split(strings.Trim(text, "\n"), "\n")
+	} else {
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, wrapText(line, r.width-len(r.prefix)-len(r.bullet))...)
+		}
+	}
+
+	indent := strings.Repeat(" ", len(r.bullet))
+	for i, line := range lines {
+		if i == 0 {
+			lines[i] = r.prefix + r.bullet + line
+		} else {
+			lines[i] = r.prefix + indent + line
+		}
+	}
+	r.paragraphs = append(r.paragraphs, paragraph{
+		text:     strings.Join(lines, "\n"),
+		listItem: r.bullet != "",
+	})
+	// only the first paragraph of a list item gets the bullet
+	r.bullet = strings.Repeat(" ", len(r.bullet))
+}
+
+// listBullet returns the marker for a list item, numbering items of ordered
+// lists.
+func listBullet(item *html.Node) string {
+	if item.Parent == nil || item.Parent.DataAtom != atom.Ol {
+		return "- "
+	}
+
+	n := 1
+	for sibling := item.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == html.ElementNode && sibling.DataAtom == atom.Li {
+			n++
+		}
+	}
+	return strconv.Itoa(n) + ". "
+}
+
+// wrapText breaks text into lines of at most width characters, only breaking
+// between words so long words such as urls are kept whole.
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	width = max(width, 20)
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// terminalWidth uses the COLUMNS variable exported by most shells, falling back
+// to a conventional terminal width.
+func terminalWidth() int {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		return defaultRenderWidth
+	}
+	return width
+}
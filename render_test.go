@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		baseURL string
+		width   int
+		want    string
+	}{
+		{
+			name:    "plain text",
+			content: "Fish &amp; chips",
+			width:   80,
+			want:    "Fish & chips",
+		},
+		{
+			name:    "paragraphs are wrapped",
+			content: "<p>The quick brown fox jumps over the lazy dog and keeps running.</p><p>Second paragraph.</p>",
+			width:   30,
+			want: lines(
+				"The quick brown fox jumps over",
+				"the lazy dog and keeps",
+				"running.",
+				"",
+				"Second paragraph.",
+			),
+		},
+		{
+			name:    "inline markup and whitespace are collapsed",
+			content: "<p>Hello   <b>bold</b>\n<i>and</i>  <em>italic</em>\ttext</p>",
+			width:   80,
+			want:    "Hello bold and italic text",
+		},
+		{
+			name:    "links become footnotes resolved against the base url",
+			content: `<p>Read <a href="/docs">the docs</a>, <a href="https://other.example/">elsewhere</a> and <a href="/docs">again</a>.</p>`,
+			baseURL: "https://example.com/blog/post",
+			width:   80,
+			want: lines(
+				"Read the docs[1], elsewhere[2] and again[1].",
+				"",
+				"Links:",
+				"[1] https://example.com/docs",
+				"[2] https://other.example/",
+			),
+		},
+		{
+			name:    "anchors and unsafe links have no footnote",
+			content: `<a href="#top">top</a> <a href="javascript:x()">script</a>`,
+			width:   80,
+			want:    "top script",
+		},
+		{
+			name:    "lists",
+			content: "<ul><li>one<ul><li>nested</li></ul></li><li>two</li></ul><ol><li>first</li><li>second</li></ol>",
+			width:   80,
+			want: lines(
+				"- one",
+				"  - nested",
+				"- two",
+				"1. first",
+				"2. second",
+			),
+		},
+		{
+			name:    "blockquotes",
+			content: "<blockquote><p>A quoted sentence that wraps.</p></blockquote>",
+			width:   20,
+			want: lines(
+				"> A quoted sentence",
+				"> that wraps.",
+			),
+		},
+		{
+			name:    "preformatted text is kept as is",
+			content: "<pre>func main() {\n    fmt.Println(\"hi\")\n}</pre>",
+			width:   20,
+			want: lines(
+				"func main() {",
+				`    fmt.Println("hi")`,
+				"}",
+			),
+		},
+		{
+			name:    "line breaks",
+			content: "first line<br>second line",
+			width:   80,
+			want:    lines("first line", "second line"),
+		},
+		{
+			name:    "images show their alt text and scripts are skipped",
+			content: `<p><img src="a.jpg" alt="A cat"> <script>alert(1)</script>caption</p>`,
+			width:   80,
+			want:    "[A cat] caption",
+		},
+		{
+			name:    "long words are not broken",
+			content: "see https://example.com/a/very/long/path/that/does/not/fit here",
+			width:   20,
+			want: lines(
+				"see",
+				"https://example.com/a/very/long/path/that/does/not/fit",
+				"here",
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderHTML(tt.content, tt.baseURL, tt.width); got != tt.want {
+				t.Errorf("renderHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func lines(text ...string) string {
+	return strings.Join(text, "\n")
+}
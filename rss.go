@@ -1,17 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"html"
 	"io"
+	"mime"
 	"net/http"
-	"time"
+	"slices"
+	"strings"
 
-	"github.com/carsondecker/gator/internal/database"
-	"github.com/google/uuid"
-	"github.com/lib/pq"
+	"golang.org/x/net/html/charset"
 )
 
 type RSSFeed struct {
@@ -19,128 +21,302 @@ type RSSFeed struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
+		Language    string    `xml:"language"`
+		TTL         string    `xml:"ttl"`
+		SkipHours   []string  `xml:"skipHours>hour"`
+		SkipDays    []string  `xml:"skipDays>day"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Guid        string   `xml:"guid"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
+
+	Enclosures   []RSSEnclosure `xml:"enclosure"`
+	MediaContent []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup   []MediaContent `xml:"http://search.yahoo.com/mrss/ group>content"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-	if err != nil {
-		return nil, err
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// MediaContent is a Media RSS <media:content> element, used by podcast and
+// video feeds alongside or instead of enclosures.
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Type     string `xml:"type,attr"`
+}
+
+// allEnclosures merges enclosures and media content, dropping duplicates of
+// the same url.
+func (item RSSItem) allEnclosures() []RSSEnclosure {
+	var enclosures []RSSEnclosure
+	seen := map[string]bool{}
+	add := func(enclosure RSSEnclosure) {
+		enclosure.URL = strings.TrimSpace(enclosure.URL)
+		if enclosure.URL == "" || seen[enclosure.URL] {
+			return
+		}
+		seen[enclosure.URL] = true
+		enclosures = append(enclosures, enclosure)
+	}
+
+	for _, enclosure := range item.Enclosures {
+		add(enclosure)
+	}
+	for _, media := range slices.Concat(item.MediaContent, item.MediaGroup) {
+		add(RSSEnclosure{URL: media.URL, Length: media.FileSize, Type: media.Type})
 	}
-	req.Header.Set("User-Agent", "gator")
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	return enclosures
+}
+
+// author returns the item's author, preferring dc:creator since <author> is
+// meant to hold an email address. For "email (Name)" only the name is kept.
+func (item RSSItem) author() string {
+	author := strings.TrimSpace(firstNonEmpty(strings.TrimSpace(item.Creator), item.Author))
+	if open := strings.Index(author, "("); open != -1 && strings.HasSuffix(author, ")") {
+		if name := strings.TrimSpace(author[open+1 : len(author)-1]); name != "" {
+			return name
+		}
+	}
+	return author
+}
+
+// categories returns the item's trimmed categories without duplicates.
+func (item RSSItem) categories() []string {
+	var categories []string
+	seen := map[string]bool{}
+	for _, category := range item.Categories {
+		category = strings.TrimSpace(category)
+		key := strings.ToLower(category)
+		if category == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// cacheValidators are the response headers used to make conditional requests
+// so unchanged feeds aren't downloaded again.
+type cacheValidators struct {
+	etag         string
+	lastModified string
+}
+
+type fetchResult struct {
+	// feed is nil when a conditional request found the feed unchanged
+	feed       *RSSFeed
+	validators cacheValidators
+	// movedTo is set when the feed permanently redirected to another url
+	movedTo string
+}
+
+func (f *fetcher) fetchFeed(ctx context.Context, feedURL string, validators cacheValidators) (*fetchResult, error) {
+	header := http.Header{}
+	if validators.etag != "" {
+		header.Set("If-None-Match", validators.etag)
+	}
+	if validators.lastModified != "" {
+		header.Set("If-Modified-Since", validators.lastModified)
+	}
+
+	res, err := f.get(ctx, feedURL, header)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	result := &fetchResult{
+		validators: validators,
+		movedTo:    res.movedTo,
+	}
+	if res.statusCode == http.StatusNotModified {
+		return result, nil
+	}
+
+	feed, err := parseFeed(res.contentType, res.body)
 	if err != nil {
 		return nil, err
 	}
 
-	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
+	result.feed = feed
+	result.validators = cacheValidators{
+		etag:         res.header.Get("ETag"),
+		lastModified: res.header.Get("Last-Modified"),
+	}
+	return result, nil
+}
+
+// parseFeed parses any supported feed format into the RSS model.
+func parseFeed(contentType string, body []byte) (*RSSFeed, error) {
+	feed, err := decodeFeed(contentType, body)
+	if err != nil {
 		return nil, err
 	}
 
+	// titles are plain text but often double escaped, descriptions are html
+	// and are decoded when rendered
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
-	for _, item := range feed.Channel.Item {
-		item.Title = html.UnescapeString(item.Title)
-		item.Description = html.UnescapeString(item.Description)
+	for i := range feed.Channel.Item {
+		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
 	}
-
-	return &feed, nil
+	return feed, nil
 }
 
-func scrapeFeeds(s *state, time_between_reqs time.Duration) error {
-	ticker := time.NewTicker(time_between_reqs)
+func decodeFeed(contentType string, body []byte) (*RSSFeed, error) {
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
 
-	feeds, err := s.db.GetFeeds(context.Background())
+	root, err := rootElement(contentType, body)
 	if err != nil {
-		return err
-	}
-	if len(feeds) == 0 {
-		return errors.New("no feeds to aggregate")
+		return nil, err
 	}
 
-	for {
-		nextFeed, err := s.db.GetNextFeedToFetch(context.Background())
+	switch root.Local {
+	case "rss":
+		decoder, err := newXMLDecoder(contentType, body)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		err = s.db.MarkFeedFetched(context.Background(), nextFeed.ID)
-		if err != nil {
-			return err
+		var feed RSSFeed
+		if err := xml.NewTokenDecoder(&rssExtensionFilter{decoder: decoder}).Decode(&feed); err != nil {
+			return nil, err
+		}
+		return &feed, nil
+	case "feed":
+		if root.Space != atomNamespace {
+			return nil, fmt.Errorf("unsupported Atom namespace %q", root.Space)
+		}
+		var feed AtomFeed
+		if err := decodeXML(contentType, body, &feed); err != nil {
+			return nil, err
+		}
+		return feed.toRSSFeed(), nil
+	case "RDF":
+		var feed RDFFeed
+		if err := decodeXML(contentType, body, &feed); err != nil {
+			return nil, err
 		}
+		return feed.toRSSFeed(), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format with root element <%s>", root.Local)
+	}
+}
+
+func rootElement(contentType string, body []byte) (xml.Name, error) {
+	decoder, err := newXMLDecoder(contentType, body)
+	if err != nil {
+		return xml.Name{}, err
+	}
 
-		feed, err := fetchFeed(context.Background(), nextFeed.Url)
+	for {
+		token, err := decoder.Token()
 		if err != nil {
-			return err
+			return xml.Name{}, errors.New("could not find root element of feed")
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
 		}
+	}
+}
 
-		for _, item := range feed.Channel.Item {
-			layouts := []string{
-				"Mon, 02 Jan 2006 15:04:05 -0700",
-				"Mon, 02 Jan 2006 15:04:05 -0700 (MST)",
-				"Mon, 02 Jan 2006 15:04:05 MST",
-				"Mon, 02 Jan 2006 15:04:05 Z0700",
-				time.RFC1123Z,
-				time.RFC822Z,
-			}
+// rssElements are the element names RSSFeed reads without a namespace.
+var rssElements = map[string]bool{
+	"channel": true, "title": true, "link": true, "description": true,
+	"language": true, "ttl": true, "skipHours": true, "skipDays": true,
+	"hour": true, "day": true, "item": true, "guid": true, "pubDate": true,
+	"author": true, "category": true, "enclosure": true,
+}
 
-			var parsedTime time.Time
-			var parseErr error
-			success := false
+// rssExtensionFilter drops extension elements that share a name with an RSS
+// element, such as <media:title> or <atom:link>. RSS elements have no
+// namespace, which struct tags can't express, so encoding/xml would otherwise
+// let the extension overwrite the RSS value.
+type rssExtensionFilter struct {
+	decoder *xml.Decoder
+	// space is the namespace of the root element, which RSS elements share
+	space   string
+	started bool
+}
 
-			for _, layout := range layouts {
-				parsedTime, parseErr = time.Parse(layout, item.PubDate)
-				if parseErr == nil {
-					success = true
-					break
-				}
-			}
-			if !success {
-				parsedTime = time.Time{}
-			}
+func (f *rssExtensionFilter) Token() (xml.Token, error) {
+	for {
+		token, err := f.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return xml.CopyToken(token), nil
+		}
+		if !f.started {
+			f.started = true
+			f.space = start.Name.Space
+		}
+		if start.Name.Space == f.space || !rssElements[start.Name.Local] {
+			return start.Copy(), nil
+		}
+		if err := f.decoder.Skip(); err != nil {
+			return nil, err
+		}
+	}
+}
 
-			if err != nil {
-				return err
-			}
+func decodeXML(contentType string, body []byte, v any) error {
+	decoder, err := newXMLDecoder(contentType, body)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(v)
+}
 
-			_, err = s.db.CreatePost(context.Background(), database.CreatePostParams{
-				ID:          uuid.New(),
-				CreatedAt:   time.Now(),
-				UpdatedAt:   time.Now(),
-				Title:       item.Title,
-				Url:         item.Link,
-				Description: item.Description,
-				PublishedAt: parsedTime,
-				FeedID:      nextFeed.ID,
-			})
+// newXMLDecoder returns a decoder that transcodes the body to UTF-8. A charset
+// in the Content-Type header takes precedence over the xml declaration.
+func newXMLDecoder(contentType string, body []byte) (*xml.Decoder, error) {
+	var reader io.Reader = bytes.NewReader(body)
 
+	transcoded := false
+	if label := contentTypeCharset(contentType); label != "" {
+		if !strings.EqualFold(label, "utf-8") {
+			charsetReader, err := charset.NewReaderLabel(label, reader)
 			if err != nil {
-				if pqErr, ok := err.(*pq.Error); ok {
-					if pqErr.Code == "23505" && pqErr.Constraint == "posts_url_key" {
-						break
-					}
-				}
-				return err
+				return nil, err
 			}
+			reader = charsetReader
+		}
+		transcoded = true
+	}
+
+	decoder := xml.NewDecoder(reader)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if transcoded {
+			return input, nil
 		}
+		return charset.NewReaderLabel(label, input)
+	}
+	return decoder, nil
+}
 
-		<-ticker.C
+func contentTypeCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
 	}
+	return params["charset"]
 }
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type parsedItem struct {
+	guid        string
+	title       string
+	link        string
+	description string
+	pubDate     string
+	content     string
+	author      string
+	categories  []string
+	enclosures  []RSSEnclosure
+}
+
+type parsedFeed struct {
+	title       string
+	link        string
+	description string
+	language    string
+	items       []parsedItem
+}
+
+func summarizeFeed(feed *RSSFeed) parsedFeed {
+	parsed := parsedFeed{
+		title:       feed.Channel.Title,
+		link:        feed.Channel.Link,
+		description: feed.Channel.Description,
+		language:    feed.Channel.Language,
+	}
+	for _, item := range feed.Channel.Item {
+		parsed.items = append(parsed.items, parsedItem{
+			guid:        item.Guid,
+			title:       item.Title,
+			link:        item.Link,
+			description: item.Description,
+			pubDate:     item.PubDate,
+			content:     item.Content,
+			author:      item.author(),
+			categories:  item.categories(),
+			enclosures:  item.allEnclosures(),
+		})
+	}
+	return parsed
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "feeds", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+		want        parsedFeed
+	}{
+		{
+			name:        "RSS 2.0",
+			fixture:     "rss.xml",
+			contentType: "application/rss+xml",
+			want: parsedFeed{
+				title:       "Example & Co",
+				link:        "https://example.com/",
+				description: "News from Example",
+				language:    "en-us",
+				items: []parsedItem{
+					{
+						guid:        "https://example.com/?p=1",
+						title:       `First "post"`,
+						link:        "https://example.com/first",
+						description: "<p>Summary of the <b>first</b> post</p>",
+						pubDate:     "Mon, 02 Jan 2006 15:04:05 -0700",
+						content:     "<p>The whole first post.</p>",
+						author:      "Jo Writer",
+						categories:  []string{"Go", "News"},
+						enclosures: []RSSEnclosure{
+							{URL: "https://example.com/first.mp3", Length: "1234", Type: "audio/mpeg"},
+							{URL: "https://example.com/first.jpg", Type: "image/jpeg"},
+						},
+					},
+					{
+						title:       "Second post",
+						link:        "https://example.com/second",
+						description: "Plain summary",
+						author:      "Sam Editor",
+					},
+				},
+			},
+		},
+		{
+			name:        "Atom",
+			fixture:     "atom.xml",
+			contentType: "application/atom+xml",
+			want: parsedFeed{
+				title:       "Example Atom",
+				link:        "https://example.com/",
+				description: "Atom news",
+				language:    "en",
+				items: []parsedItem{
+					{
+						guid:        "tag:example.com,2006:1",
+						title:       "Atom entry",
+						link:        "https://example.com/atom-entry",
+						description: "<p>Atom summary</p>",
+						pubDate:     "2006-01-02T22:04:05Z",
+						content:     `<div xmlns="http://www.w3.org/1999/xhtml"><p>Atom content</p></div>`,
+						author:      "Feed Author",
+						categories:  []string{"Go", "news"},
+						enclosures: []RSSEnclosure{
+							{URL: "https://example.com/atom.mp3", Length: "5678", Type: "audio/mpeg"},
+							{URL: "https://example.com/atom.mp4", Length: "9012", Type: "video/mp4"},
+						},
+					},
+					{
+						guid:        "tag:example.com,2006:2",
+						title:       "Updated only",
+						link:        "https://example.com/updated-only",
+						description: "Text content",
+						pubDate:     "2006-01-04T00:00:00Z",
+						content:     "Text content",
+						author:      "Entry Author, Second Author",
+					},
+				},
+			},
+		},
+		{
+			name:        "RSS 1.0",
+			fixture:     "rdf.xml",
+			contentType: "application/rdf+xml",
+			want: parsedFeed{
+				title:       "Example RDF",
+				link:        "https://example.com/",
+				description: "RDF news",
+				language:    "de",
+				items: []parsedItem{
+					{
+						guid:        "https://example.com/rdf-item",
+						title:       "RDF item",
+						link:        "https://example.com/rdf-item",
+						description: "RDF description",
+						pubDate:     "2006-01-02T22:04:05Z",
+						content:     "<p>RDF content</p>",
+						author:      "Rita Author",
+						categories:  []string{"Science"},
+					},
+				},
+			},
+		},
+		{
+			name:        "JSON Feed",
+			fixture:     "feed.json",
+			contentType: "application/feed+json",
+			want: parsedFeed{
+				title:       "Example JSON",
+				link:        "https://example.com/",
+				description: "JSON news",
+				language:    "fr",
+				items: []parsedItem{
+					{
+						guid:        "https://example.com/json-item",
+						title:       "JSON item",
+						link:        "https://example.com/json-item",
+						description: "JSON summary",
+						pubDate:     "2006-01-02T22:04:05Z",
+						content:     "<p>JSON content</p>",
+						author:      "Item Author",
+						categories:  []string{"Go", "News"},
+						enclosures: []RSSEnclosure{
+							{URL: "https://example.com/json.mp3", Length: "42", Type: "audio/mpeg"},
+						},
+					},
+					{
+						guid:        "2",
+						title:       "Numeric id",
+						link:        "https://example.com/numeric-id",
+						description: "Text only",
+						pubDate:     "2006-01-03T00:00:00Z",
+						content:     "Text only",
+						author:      "Feed Author",
+					},
+					{
+						guid:        "legacy",
+						title:       "Version 1.0 author",
+						link:        "https://example.com/external",
+						description: "Old style",
+						content:     "Old style",
+						author:      "Old Author",
+					},
+				},
+			},
+		},
+		{
+			name:        "ISO-8859-1 from the xml declaration",
+			fixture:     "latin1.xml",
+			contentType: "text/xml",
+			want: parsedFeed{
+				title:       "Café news",
+				link:        "https://example.com/",
+				description: "Crème brûlée",
+				items: []parsedItem{
+					{
+						title:       "Grüße",
+						link:        "https://example.com/gruesse",
+						description: "¿Qué tal?",
+					},
+				},
+			},
+		},
+		{
+			name:        "ISO-8859-1 from the Content-Type header",
+			fixture:     "latin1.xml",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			want: parsedFeed{
+				title:       "Café news",
+				link:        "https://example.com/",
+				description: "Crème brûlée",
+				items: []parsedItem{
+					{
+						title:       "Grüße",
+						link:        "https://example.com/gruesse",
+						description: "¿Qué tal?",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed(tt.contentType, readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+			if got := summarizeFeed(feed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeed() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedUTF8HeaderOverridesDeclaration(t *testing.T) {
+	body := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?><rss><channel><title>Café</title></channel></rss>`)
+
+	feed, err := parseFeed("application/rss+xml; charset=utf-8", body)
+	if err != nil {
+		t.Fatalf("parseFeed() error = %v", err)
+	}
+	if feed.Channel.Title != "Café" {
+		t.Errorf("title = %q, want %q", feed.Channel.Title, "Café")
+	}
+}
+
+func TestParseFeedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"html page", "<!DOCTYPE html><html><body>not a feed</body></html>"},
+		{"unknown root element", `<?xml version="1.0"?><opml version="2.0"></opml>`},
+		{"empty body", ""},
+		{"invalid json", `{"items": [`},
+		{"json without a version", `{"name": "x", "items": []}`},
+		{"json with another version", `{"version": "1.0", "items": [{"id": "1"}]}`},
+		{"atom without the atom namespace", `<?xml version="1.0"?><feed xmlns="http://purl.org/atom/ns#"><title>x</title></feed>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseFeed("", []byte(tt.body)); err == nil {
+				t.Error("parseFeed() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestParseFeedLatin1FixtureIsNotUTF8(t *testing.T) {
+	// guards against the fixture being re-saved as utf-8, which would make the
+	// charset tests pass without transcoding anything
+	if bytes.Contains(readFixture(t, "latin1.xml"), []byte("Café")) {
+		t.Fatal("latin1.xml must be encoded as ISO-8859-1")
+	}
+}
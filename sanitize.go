@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// strippedElements are removed together with everything inside them.
+var strippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Link:     true,
+	atom.Meta:     true,
+	atom.Template: true,
+}
+
+// urlAttributes may only hold http(s), mailto or relative urls.
+var urlAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"srcset": true,
+	"action": true,
+}
+
+// sanitizeHTML removes scripts, styles, embedded content and tracking pixels
+// from a feed's html, along with event handlers and javascript: urls. Text
+// without markup is returned unchanged.
+func sanitizeHTML(content string) string {
+	if !strings.ContainsAny(content, "<&") {
+		return content
+	}
+
+	parent := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(content), parent)
+	if err != nil {
+		return content
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		if !sanitizeNode(node) {
+			continue
+		}
+		if err := html.Render(&buf, node); err != nil {
+			return content
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// sanitizeNode cleans node and its children in place, and reports whether the
+// node itself should be kept.
+func sanitizeNode(node *html.Node) bool {
+	switch node.Type {
+	case html.CommentNode, html.DoctypeNode:
+		return false
+	case html.ElementNode:
+		if strippedElements[node.DataAtom] {
+			return false
+		}
+		if node.DataAtom == atom.Img && isTrackingPixel(node) {
+			return false
+		}
+		node.Attr = safeAttributes(node.Attr)
+	}
+
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if !sanitizeNode(child) {
+			node.RemoveChild(child)
+		}
+		child = next
+	}
+	return true
+}
+
+func safeAttributes(attrs []html.Attribute) []html.Attribute {
+	safe := attrs[:0]
+	for _, a := range attrs {
+		key := strings.ToLower(a.Key)
+		if strings.HasPrefix(key, "on") || key == "style" {
+			continue
+		}
+		if urlAttributes[key] && !isSafeURL(a.Val) {
+			continue
+		}
+		safe = append(safe, a)
+	}
+	return safe
+}
+
+func isSafeURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+// isTrackingPixel reports whether an image is invisible, which is how feeds
+// embed analytics beacons.
+func isTrackingPixel(node *html.Node) bool {
+	width, widthErr := strconv.Atoi(strings.TrimSuffix(attr(node, "width"), "px"))
+	height, heightErr := strconv.Atoi(strings.TrimSuffix(attr(node, "height"), "px"))
+	if widthErr == nil && heightErr == nil && width <= 1 && height <= 1 {
+		return true
+	}
+
+	style := strings.ToLower(strings.ReplaceAll(attr(node, "style"), " ", ""))
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
@@ -0,0 +1,81 @@
+package main
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "plain text is unchanged",
+			content: "Just some text",
+			want:    "Just some text",
+		},
+		{
+			name:    "safe markup is kept",
+			content: `<p>Hello <a href="https://example.com/">world</a></p>`,
+			want:    `<p>Hello <a href="https://example.com/">world</a></p>`,
+		},
+		{
+			name:    "scripts and styles are removed with their contents",
+			content: `<p>Before</p><script>alert("x")</script><style>p { color: red }</style><p>After</p>`,
+			want:    `<p>Before</p><p>After</p>`,
+		},
+		{
+			name:    "nested embeds are removed",
+			content: `<div><iframe src="https://example.com/embed"></iframe><p>Text</p><noscript><img src="x.gif"/></noscript></div>`,
+			want:    `<div><p>Text</p></div>`,
+		},
+		{
+			name:    "tracking pixels are removed",
+			content: `<p>Text</p><img src="https://tracker.example/p.gif?id=1" width="1" height="1"/><img src="https://example.com/photo.jpg" width="640" height="480"/>`,
+			want:    `<p>Text</p><img src="https://example.com/photo.jpg" width="640" height="480"/>`,
+		},
+		{
+			name:    "hidden images are removed",
+			content: `<img src="https://tracker.example/p.gif" style="display: none"/>`,
+			want:    ``,
+		},
+		{
+			name:    "event handlers and inline styles are removed",
+			content: `<p onclick="steal()" style="color: red" class="intro">Text</p>`,
+			want:    `<p class="intro">Text</p>`,
+		},
+		{
+			name:    "javascript urls are removed",
+			content: `<a href="javascript:steal()">link</a> <a href="JavaScript:steal()">other</a> <a href="/relative">ok</a>`,
+			want:    `<a>link</a> <a>other</a> <a href="/relative">ok</a>`,
+		},
+		{
+			name:    "comments are removed",
+			content: `<p>Text<!-- tracking id 123 --></p>`,
+			want:    `<p>Text</p>`,
+		},
+		{
+			name:    "entities are kept escaped",
+			content: `Fish &amp; chips`,
+			want:    `Fish &amp; chips`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.content); got != tt.want {
+				t.Errorf("sanitizeHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTMLIsStable(t *testing.T) {
+	// post hashes are computed from sanitized html, so sanitizing twice must
+	// not change it again
+	content := `<p onclick="x()">Hello <b>world</b><img src="p.gif" width="1" height="1"><br>Fish &amp; chips</p>`
+
+	once := sanitizeHTML(content)
+	if twice := sanitizeHTML(once); twice != once {
+		t.Errorf("sanitizeHTML() is not stable:\n%s\n%s", once, twice)
+	}
+}
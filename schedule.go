@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/carsondecker/gator/internal/database"
+)
+
+const (
+	minPollInterval = 15 * time.Minute
+	maxPollInterval = 24 * time.Hour
+)
+
+var weekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// schedulingHints are the RSS channel elements a publisher uses to ask
+// aggregators not to poll them too often. Hours and days are in GMT.
+type schedulingHints struct {
+	ttlMinutes sql.NullInt32
+	skipHours  []int32
+	skipDays   []string
+}
+
+// parseSchedulingHints reads the hints leniently, ignoring malformed values
+// rather than failing the whole feed over them.
+func parseSchedulingHints(feed *RSSFeed) schedulingHints {
+	hints := schedulingHints{
+		skipHours: []int32{},
+		skipDays:  []string{},
+	}
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(feed.Channel.TTL)); err == nil && ttl > 0 {
+		hints.ttlMinutes = sql.NullInt32{Int32: int32(ttl), Valid: true}
+	}
+
+	for _, value := range feed.Channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// some publishers count hours 1-24 instead of 0-23
+		hour %= 24
+		if !slices.Contains(hints.skipHours, int32(hour)) {
+			hints.skipHours = append(hints.skipHours, int32(hour))
+		}
+	}
+
+	for _, value := range feed.Channel.SkipDays {
+		for _, weekday := range weekdays {
+			if strings.EqualFold(strings.TrimSpace(value), weekday) && !slices.Contains(hints.skipDays, weekday) {
+				hints.skipDays = append(hints.skipDays, weekday)
+			}
+		}
+	}
+
+	slices.Sort(hints.skipHours)
+	slices.SortFunc(hints.skipDays, func(a, b string) int {
+		return slices.Index(weekdays, a) - slices.Index(weekdays, b)
+	})
+
+	return hints
+}
+
+func (h schedulingHints) matches(feed database.Feed) bool {
+	return h.ttlMinutes == feed.TtlMinutes &&
+		slices.Equal(h.skipHours, feed.SkipHours) &&
+		slices.Equal(h.skipDays, feed.SkipDays)
+}
+
+// scheduleNextFetch records a successful fetch and pushes the feed's next fetch
+// out according to how often it has historically published.
+func scheduleNextFetch(ctx context.Context, s *state, feed database.Feed) error {
+	averageInterval, err := s.db.GetFeedPostingInterval(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.RecordFeedSuccess(ctx, database.RecordFeedSuccessParams{
+		PollIntervalSeconds: int32(pollInterval(averageInterval).Seconds()),
+		ID:                  feed.ID,
+	})
+}
+
+// pollInterval polls twice per average gap between posts, so a feed that posts
+// daily is checked every 12 hours while an hourly feed is checked as often as
+// minPollInterval allows.
+func pollInterval(averageIntervalSeconds float64) time.Duration {
+	if averageIntervalSeconds <= 0 {
+		return minPollInterval
+	}
+
+	interval := time.Duration(averageIntervalSeconds/2) * time.Second
+	return min(max(interval, minPollInterval), maxPollInterval)
+}
+
+// recordFeedFailure backs the feed off exponentially, waiting at least as long
+// as a rate limiting server asked. Feeds that are gone are disabled for good.
+func recordFeedFailure(ctx context.Context, s *state, feed database.Feed, fetchErr error) error {
+	lastError := sql.NullString{String: fetchErr.Error(), Valid: true}
+	lastStatusCode := sql.NullInt32{}
+	retryAfter := time.Duration(0)
+
+	var statusErr *statusError
+	if errors.As(fetchErr, &statusErr) {
+		lastStatusCode = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+		retryAfter = statusErr.RetryAfter
+
+		if statusErr.StatusCode == http.StatusGone {
+			fmt.Printf("feed %s is gone, disabling it\n", feed.Url)
+			return s.db.DisableFeed(ctx, database.DisableFeedParams{
+				ID:             feed.ID,
+				LastError:      lastError,
+				LastStatusCode: lastStatusCode,
+			})
+		}
+	}
+
+	return s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		LastError:         lastError,
+		LastStatusCode:    lastStatusCode,
+		RetryAfterSeconds: int32(min(retryAfter, maxPollInterval).Seconds()),
+		ID:                feed.ID,
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSchedulingHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       string
+		skipHours []string
+		skipDays  []string
+		want      schedulingHints
+	}{
+		{
+			name: "no hints",
+			want: schedulingHints{skipHours: []int32{}, skipDays: []string{}},
+		},
+		{
+			name: "ttl",
+			ttl:  " 60 ",
+			want: schedulingHints{
+				ttlMinutes: sql.NullInt32{Int32: 60, Valid: true},
+				skipHours:  []int32{},
+				skipDays:   []string{},
+			},
+		},
+		{
+			name: "malformed ttl is ignored",
+			ttl:  "soon",
+			want: schedulingHints{skipHours: []int32{}, skipDays: []string{}},
+		},
+		{
+			name: "zero ttl is ignored",
+			ttl:  "0",
+			want: schedulingHints{skipHours: []int32{}, skipDays: []string{}},
+		},
+		{
+			name:      "hours are sorted and deduplicated",
+			skipHours: []string{"23", " 1 ", "5", "1"},
+			want:      schedulingHints{skipHours: []int32{1, 5, 23}, skipDays: []string{}},
+		},
+		{
+			name:      "hour 24 is midnight",
+			skipHours: []string{"24", "0", "12"},
+			want:      schedulingHints{skipHours: []int32{0, 12}, skipDays: []string{}},
+		},
+		{
+			name:      "out of range and malformed hours are ignored",
+			skipHours: []string{"-1", "25", "noon", "3"},
+			want:      schedulingHints{skipHours: []int32{3}, skipDays: []string{}},
+		},
+		{
+			name:     "days are case insensitive, sorted and deduplicated",
+			skipDays: []string{"sunday", " SATURDAY ", "Monday", "Sunday"},
+			want:     schedulingHints{skipHours: []int32{}, skipDays: []string{"Monday", "Saturday", "Sunday"}},
+		},
+		{
+			name:     "unknown days are ignored",
+			skipDays: []string{"Mon", "Someday", "Friday"},
+			want:     schedulingHints{skipHours: []int32{}, skipDays: []string{"Friday"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &RSSFeed{}
+			feed.Channel.TTL = tt.ttl
+			feed.Channel.SkipHours = tt.skipHours
+			feed.Channel.SkipDays = tt.skipDays
+
+			if got := parseSchedulingHints(feed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSchedulingHints() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		name            string
+		averageInterval float64
+		want            time.Duration
+	}{
+		{"no posting history", 0, minPollInterval},
+		{"negative average", -60, minPollInterval},
+		{"daily posts are polled twice a day", (24 * time.Hour).Seconds(), 12 * time.Hour},
+		{"hourly posts are polled every half hour", time.Hour.Seconds(), 30 * time.Minute},
+		{"frequent posts are clamped to the minimum", (10 * time.Minute).Seconds(), minPollInterval},
+		{"exactly twice the minimum", (2 * minPollInterval).Seconds(), minPollInterval},
+		{"rare posts are clamped to the maximum", (30 * 24 * time.Hour).Seconds(), maxPollInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pollInterval(tt.averageInterval); got != tt.want {
+				t.Errorf("pollInterval(%v) = %v, want %v", tt.averageInterval, got, tt.want)
+			}
+		})
+	}
+}
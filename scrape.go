@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/carsondecker/gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type scrapeStats struct {
+	feedsFetched atomic.Int64
+	feedsFailed  atomic.Int64
+	postsCreated atomic.Int64
+	postsUpdated atomic.Int64
+}
+
+func scrapeFeeds(ctx context.Context, s *state, time_between_reqs time.Duration, concurrency int) error {
+	ticker := time.NewTicker(time_between_reqs)
+	defer ticker.Stop()
+
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return err
+	}
+	if len(feeds) == 0 {
+		return errors.New("no feeds to aggregate")
+	}
+
+	// each tick hands out up to one fetch per worker, so a slow feed only
+	// holds up its own worker instead of the whole aggregator
+	var stats scrapeStats
+	var wg sync.WaitGroup
+	jobs := make(chan struct{}, concurrency)
+	errs := make(chan error, concurrency)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := scrapeNextFeed(ctx, s, &stats); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	err = nil
+	for err == nil && ctx.Err() == nil {
+		for range concurrency {
+			select {
+			case jobs <- struct{}{}:
+			default:
+			}
+		}
+
+		select {
+		case err = <-errs:
+		case <-ctx.Done():
+			fmt.Println("shutting down, waiting for in-flight feeds to finish")
+		case <-ticker.C:
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	fmt.Printf("fetched %d feeds (%d failed), saved %d new posts and %d updated posts\n",
+		stats.feedsFetched.Load(), stats.feedsFailed.Load(), stats.postsCreated.Load(), stats.postsUpdated.Load())
+
+	return err
+}
+
+func scrapeNextFeed(ctx context.Context, s *state, stats *scrapeStats) error {
+	// claiming a feed pushes next_fetch_at out, so other agg processes skip it
+	// while it is being scraped. The outcome replaces that lease, and if this
+	// process dies first the feed is picked up again like any other due feed.
+	nextFeed, err := s.db.GetNextFeedToFetch(ctx, int32(minPollInterval/time.Second))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+			return nil
+		}
+		return checkDBError(err)
+	}
+
+	nextFeed, err = scrapeFeed(ctx, s, nextFeed, stats)
+	if errors.Is(err, errDatabaseUnavailable) {
+		return err
+	}
+	if err != nil && ctx.Err() != nil {
+		// interrupted by shutdown, not a problem with the feed itself
+		return nil
+	}
+
+	// record the outcome even if a shutdown starts in the meantime
+	ctx = context.WithoutCancel(ctx)
+	if err == nil {
+		stats.feedsFetched.Add(1)
+		err = scheduleNextFetch(ctx, s, nextFeed)
+	} else {
+		stats.feedsFailed.Add(1)
+		fmt.Printf("failed to scrape feed %s: %v\n", nextFeed.Url, err)
+		err = recordFeedFailure(ctx, s, nextFeed, err)
+	}
+	if err != nil {
+		err = checkDBError(err)
+		if errors.Is(err, errDatabaseUnavailable) {
+			return err
+		}
+		fmt.Printf("failed to record status of feed %s: %v\n", nextFeed.Url, err)
+	}
+
+	return nil
+}
+
+// scrapeFeed fetches a feed and saves new or changed items as posts. Once the
+// feed has been downloaded the writes run to completion even if ctx is
+// cancelled. It returns the feed as stored after following any permanent
+// redirect, which may be a different feed it was merged into.
+func scrapeFeed(ctx context.Context, s *state, nextFeed database.Feed, stats *scrapeStats) (database.Feed, error) {
+	validators := cacheValidators{
+		etag:         nextFeed.Etag.String,
+		lastModified: nextFeed.LastModified.String,
+	}
+	result, err := s.fetcher.fetchFeed(ctx, nextFeed.Url, validators)
+	if err != nil {
+		return nextFeed, err
+	}
+
+	fetchCtx := ctx
+	ctx = context.WithoutCancel(ctx)
+
+	if result.movedTo != "" && result.movedTo != nextFeed.Url {
+		movedFeed, err := moveFeed(ctx, s, nextFeed, result.movedTo)
+		if err != nil {
+			return nextFeed, checkDBError(err)
+		}
+		nextFeed = movedFeed
+	}
+
+	if result.feed == nil {
+		return nextFeed, nil
+	}
+	feed := result.feed
+
+	hints := parseSchedulingHints(feed)
+	if !hints.matches(nextFeed) {
+		err = s.db.SetFeedSchedulingHints(ctx, database.SetFeedSchedulingHintsParams{
+			ID:         nextFeed.ID,
+			TtlMinutes: hints.ttlMinutes,
+			SkipHours:  hints.skipHours,
+			SkipDays:   hints.skipDays,
+		})
+		if err != nil {
+			return nextFeed, checkDBError(err)
+		}
+	}
+
+	fetchedAt := time.Now()
+	seen := make(map[string]bool, len(feed.Channel.Item))
+	for _, item := range feed.Channel.Item {
+		publishedAt, estimated := parsePubDate(item.PubDate, fetchedAt)
+
+		// items without a guid are identified by their link instead
+		guid := firstNonEmpty(strings.TrimSpace(item.Guid), strings.TrimSpace(item.Link))
+		if guid == "" {
+			continue
+		}
+		// some feeds repeat an item, keep the first copy rather than
+		// counting the others as revisions of it
+		if seen[guid] {
+			continue
+		}
+		seen[guid] = true
+
+		// posts stored before guids were tracked are keyed by their url, so
+		// take them over rather than saving the item a second time
+		if guid != item.Link && item.Link != "" {
+			err = s.db.AdoptLegacyPostGuid(ctx, database.AdoptLegacyPostGuidParams{
+				Guid:   guid,
+				FeedID: nextFeed.ID,
+				Url:    item.Link,
+			})
+			if err != nil {
+				return nextFeed, checkDBError(err)
+			}
+		}
+
+		// hash what is stored, so markup the sanitizer drops, such as
+		// tracking pixels with changing urls, doesn't count as an update
+		description := sanitizeHTML(item.Description)
+		content := sanitizeHTML(item.Content)
+
+		post, err := s.db.UpsertPost(ctx, database.UpsertPostParams{
+			ID:                   uuid.New(),
+			CreatedAt:            time.Now(),
+			UpdatedAt:            time.Now(),
+			Title:                item.Title,
+			Url:                  item.Link,
+			Description:          description,
+			PublishedAt:          publishedAt,
+			FeedID:               nextFeed.ID,
+			Guid:                 guid,
+			ContentHash:          contentHash(item.Title, description, content),
+			Content:              content,
+			Author:               item.author(),
+			PublishedAtEstimated: estimated,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// already stored and unchanged
+			continue
+		}
+		if err != nil {
+			return nextFeed, checkDBError(err)
+		}
+
+		if post.Inserted {
+			stats.postsCreated.Add(1)
+		} else {
+			stats.postsUpdated.Add(1)
+		}
+
+		if nextFeed.FullArticle && post.Inserted && item.Link != "" {
+			err = saveArticle(fetchCtx, ctx, s, post.ID, item.Link)
+			if err != nil {
+				return nextFeed, err
+			}
+		}
+
+		// replace rather than merge so categories dropped by the feed go away
+		err = s.db.DeletePostCategories(ctx, post.ID)
+		if err != nil {
+			return nextFeed, checkDBError(err)
+		}
+		for _, category := range item.categories() {
+			err = s.db.CreatePostCategory(ctx, database.CreatePostCategoryParams{
+				PostID: post.ID,
+				Name:   category,
+			})
+			if err != nil {
+				return nextFeed, checkDBError(err)
+			}
+		}
+
+		for _, enclosure := range item.allEnclosures() {
+			length, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+			if err != nil || length < 0 {
+				length = 0
+			}
+
+			err = s.db.CreateEnclosure(ctx, database.CreateEnclosureParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Url:       enclosure.URL,
+				MimeType:  strings.TrimSpace(enclosure.Type),
+				Length:    length,
+				PostID:    post.ID,
+			})
+			if err != nil {
+				return nextFeed, checkDBError(err)
+			}
+		}
+	}
+
+	// only remember the new validators once every item is stored, otherwise a
+	// failure above would make the next request come back 304 and the
+	// remaining items would never be saved
+	if result.validators != validators {
+		err = s.db.SetFeedCacheValidators(ctx, database.SetFeedCacheValidatorsParams{
+			ID:           nextFeed.ID,
+			Etag:         sql.NullString{String: result.validators.etag, Valid: result.validators.etag != ""},
+			LastModified: sql.NullString{String: result.validators.lastModified, Valid: result.validators.lastModified != ""},
+		})
+		if err != nil {
+			return nextFeed, checkDBError(err)
+		}
+	}
+
+	return nextFeed, nil
+}
+
+// saveArticle downloads a post's page and stores the article extracted from it.
+// Failing to extract an article only leaves the post with its feed content, but
+// database errors are returned. Downloads stop once fetchCtx is cancelled.
+func saveArticle(fetchCtx, ctx context.Context, s *state, postID uuid.UUID, link string) error {
+	if fetchCtx.Err() != nil {
+		return nil
+	}
+
+	article, err := s.fetcher.fetchArticle(fetchCtx, link)
+	if err != nil {
+		fmt.Printf("failed to fetch full article %s: %v\n", link, err)
+		return nil
+	}
+
+	err = s.db.SetPostArticle(ctx, database.SetPostArticleParams{
+		ID:      postID,
+		Article: article,
+	})
+	return checkDBError(err)
+}
+
+// contentHash identifies the version of a post from the values stored for it,
+// matching the hash computed for existing posts by the post content migration.
+func contentHash(title, description, content string) string {
+	sum := sha256.Sum256([]byte(title + "\n" + description + "\n" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// moveFeed points a feed at the url it permanently redirected to. If another
+// feed already uses that url the two are merged, keeping the existing one, and
+// the feed to continue scraping into is returned.
+func moveFeed(ctx context.Context, s *state, feed database.Feed, newURL string) (database.Feed, error) {
+	existing, err := s.db.GetFeedByUrl(ctx, newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Printf("feed %s moved permanently to %s\n", feed.Url, newURL)
+		err = s.db.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{
+			ID:  feed.ID,
+			Url: newURL,
+		})
+		feed.Url = newURL
+		return feed, err
+	}
+	if err != nil {
+		return feed, err
+	}
+
+	fmt.Printf("feed %s moved permanently to %s, merging it into the existing feed\n", feed.Url, newURL)
+
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return feed, err
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	err = qtx.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return feed, err
+	}
+
+	err = qtx.MovePosts(ctx, database.MovePostsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return feed, err
+	}
+
+	err = qtx.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return feed, err
+	}
+
+	return existing, tx.Commit()
+}
+
+// errDatabaseUnavailable marks errors that stop the aggregator as a whole, as
+// opposed to failures of a single feed which are recorded and skipped.
+var errDatabaseUnavailable = errors.New("database unavailable")
+
+func checkDBError(err error) error {
+	if isConnectionError(err) {
+		return fmt.Errorf("%w: %w", errDatabaseUnavailable, err)
+	}
+	return err
+}
+
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		class := pqErr.Code.Class()
+		return class == "08" || class == "57"
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+-- name: CreateDownload :one
+INSERT INTO downloads (id, created_at, updated_at, path, size, user_id, enclosure_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: GetLatestEpisodesForUser :many
+SELECT e.id AS enclosure_id, e.url, e.mime_type, p.title AS post_title, f.name AS feed_name, d.id AS download_id
+FROM (
+    SELECT p.id, p.feed_id, ROW_NUMBER() OVER (PARTITION BY p.feed_id ORDER BY p.published_at DESC) AS episode_number
+    FROM posts p
+        JOIN feed_follows ff
+            ON ff.feed_id = p.feed_id
+    WHERE ff.user_id = @user_id
+        AND EXISTS (
+            SELECT 1
+            FROM enclosures e
+            WHERE e.post_id = p.id AND (e.mime_type ILIKE 'audio/%' OR e.mime_type ILIKE 'video/%')
+        )
+) latest
+    JOIN posts p
+        ON p.id = latest.id
+    JOIN feeds f
+        ON f.id = latest.feed_id
+    JOIN enclosures e
+        ON e.post_id = p.id
+    LEFT JOIN downloads d
+        ON d.enclosure_id = e.id AND d.user_id = @user_id
+WHERE latest.episode_number <= @keep::BIGINT
+    AND (e.mime_type ILIKE 'audio/%' OR e.mime_type ILIKE 'video/%')
+ORDER BY f.name, latest.episode_number;
+
+-- name: GetExpiredDownloadsForUser :many
+SELECT d.*
+FROM downloads d
+    JOIN enclosures e
+        ON e.id = d.enclosure_id
+    LEFT JOIN (
+        SELECT p.id, ROW_NUMBER() OVER (PARTITION BY p.feed_id ORDER BY p.published_at DESC) AS episode_number
+        FROM posts p
+        WHERE EXISTS (
+            SELECT 1
+            FROM enclosures e
+            WHERE e.post_id = p.id AND (e.mime_type ILIKE 'audio/%' OR e.mime_type ILIKE 'video/%')
+        )
+    ) latest
+        ON latest.id = e.post_id
+WHERE d.user_id = @user_id
+    AND (
+        latest.episode_number > @keep::BIGINT
+        OR latest.id IS NULL
+        OR NOT (e.mime_type ILIKE 'audio/%' OR e.mime_type ILIKE 'video/%')
+    );
+
+-- name: DeleteDownload :exec
+DELETE FROM downloads
+WHERE id = $1;
\ No newline at end of file
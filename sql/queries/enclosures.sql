@@ -0,0 +1,23 @@
+-- name: CreateEnclosure :exec
+INSERT INTO enclosures (id, created_at, updated_at, url, mime_type, length, post_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (post_id, url) DO UPDATE
+SET mime_type = EXCLUDED.mime_type,
+    length = EXCLUDED.length,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: GetEnclosuresForPosts :many
+SELECT e.*, d.path AS download_path
+FROM enclosures e
+    LEFT JOIN downloads d
+        ON d.enclosure_id = e.id AND d.user_id = @user_id
+WHERE e.post_id = ANY(@post_ids::UUID[])
+ORDER BY e.created_at;
\ No newline at end of file
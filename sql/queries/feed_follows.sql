@@ -28,4 +28,14 @@ WHERE u.name = $1;
 
 -- name: UnfollowFeedForUser :exec
 DELETE FROM feed_follows ff
-WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = @to_feed_id, updated_at = CURRENT_TIMESTAMP
+WHERE feed_id = @from_feed_id
+    AND user_id NOT IN (
+        SELECT user_id
+        FROM feed_follows
+        WHERE feed_id = @to_feed_id
+    );
\ No newline at end of file
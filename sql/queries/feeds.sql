@@ -1,12 +1,16 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, title, description, site_url, language)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 )
 RETURNING *;
 
@@ -14,7 +18,7 @@ RETURNING *;
 SELECT * FROM feeds;
 
 -- name: GetFeedsWithUser :many
-SELECT f.id, f.name, f.url, u.name AS user_name
+SELECT f.id, f.name, f.url, f.title, f.description, f.site_url, f.language, f.consecutive_failures, f.last_error, f.next_fetch_at, f.last_status_code, f.disabled_at, f.full_article, u.name AS user_name
 FROM feeds f
     JOIN users u
         ON f.user_id = u.id;
@@ -24,13 +28,79 @@ SELECT *
 FROM feeds
 WHERE url = $1;
 
--- name: MarkFeedFetched :exec
+-- name: GetNextFeedToFetch :one
 UPDATE feeds
-SET updated_at = CURRENT_TIMESTAMP, last_fetched_at = CURRENT_TIMESTAMP
+SET updated_at = CURRENT_TIMESTAMP, last_fetched_at = CURRENT_TIMESTAMP,
+    next_fetch_at = CURRENT_TIMESTAMP + @lease_seconds::INTEGER * INTERVAL '1 second'
+WHERE id = (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+        AND (next_fetch_at IS NULL OR next_fetch_at <= CURRENT_TIMESTAMP)
+        AND (ttl_minutes IS NULL OR last_fetched_at IS NULL
+            OR last_fetched_at + ttl_minutes * INTERVAL '1 minute' <= CURRENT_TIMESTAMP)
+        AND NOT EXTRACT(HOUR FROM CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::INTEGER = ANY(skip_hours)
+        AND NOT TO_CHAR(CURRENT_TIMESTAMP AT TIME ZONE 'UTC', 'FMDay') = ANY(skip_days)
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT *
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = @last_error,
+    last_status_code = @last_status_code,
+    next_fetch_at = CURRENT_TIMESTAMP + GREATEST(
+        INTERVAL '1 minute' * POWER(2, LEAST(consecutive_failures, 10)),
+        @retry_after_seconds::INTEGER * INTERVAL '1 second'
+    )
+WHERE id = @id;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_status_code = NULL,
+    next_fetch_at = CURRENT_TIMESTAMP + @poll_interval_seconds::INTEGER * INTERVAL '1 second'
+WHERE id = @id;
+
+-- name: SetFeedFullArticle :exec
+UPDATE feeds
+SET full_article = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SetFeedSchedulingHints :exec
+UPDATE feeds
+SET ttl_minutes = $2, skip_hours = $3, skip_days = $4
+WHERE id = $1;
+
+-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = CURRENT_TIMESTAMP, last_error = $2, last_status_code = $3
+WHERE id = $1;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    consecutive_failures = 0,
+    last_error = NULL,
+    last_status_code = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT (post_id, name) DO NOTHING;
+
+-- name: DeletePostCategories :exec
+DELETE FROM post_categories
+WHERE post_id = $1;
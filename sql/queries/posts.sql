@@ -1,5 +1,17 @@
--- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
+-- name: AdoptLegacyPostGuid :exec
+UPDATE posts
+SET guid = @guid
+WHERE feed_id = @feed_id
+    AND url = @url
+    AND guid = url
+    AND NOT EXISTS (
+        SELECT 1
+        FROM posts existing
+        WHERE existing.feed_id = @feed_id AND existing.guid = @guid
+    );
+
+-- name: UpsertPost :one
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, published_at_estimated)
 VALUES (
     $1,
     $2,
@@ -8,14 +20,74 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
 )
-RETURNING *;
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    content_hash = EXCLUDED.content_hash,
+    updated_at = EXCLUDED.updated_at,
+    revisions = posts.revisions + CASE
+        WHEN posts.content_hash = '' THEN 0
+        WHEN posts.content = '' AND posts.title = EXCLUDED.title AND posts.description = EXCLUDED.description THEN 0
+        ELSE 1
+    END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id, revisions, (xmax = 0) AS inserted;
+
+-- name: GetPostForUserByUrl :one
+SELECT p.*
+FROM posts p
+    JOIN feed_follows ff
+        ON ff.feed_id = p.feed_id
+WHERE ff.user_id = $1 AND p.url = $2
+ORDER BY p.published_at DESC
+LIMIT 1;
 
 -- name: GetPostsForUser :many
 SELECT p.*
 FROM feed_follows ff
     JOIN posts p
         ON ff.feed_id = p.feed_id
-WHERE user_id = $1
-LIMIT $2;
\ No newline at end of file
+WHERE ff.user_id = @user_id
+    AND (@author::TEXT = '' OR p.author ILIKE '%' || @author::TEXT || '%')
+    AND (@category::TEXT = '' OR EXISTS (
+        SELECT 1
+        FROM post_categories pc
+        WHERE pc.post_id = p.id AND LOWER(pc.name) = LOWER(@category::TEXT)
+    ))
+ORDER BY p.published_at DESC
+LIMIT @post_limit;
+
+-- name: SetPostArticle :exec
+UPDATE posts
+SET article = $2
+WHERE id = $1;
+
+-- name: GetFeedPostingInterval :one
+SELECT CAST(COALESCE(EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)) / NULLIF(COUNT(*) - 1, 0), 0) AS FLOAT8) AS average_interval_seconds
+FROM (
+    SELECT published_at
+    FROM posts
+    WHERE feed_id = $1 AND NOT published_at_estimated
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent_posts;
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = @to_feed_id
+WHERE feed_id = @from_feed_id
+    AND guid NOT IN (
+        SELECT guid
+        FROM posts
+        WHERE feed_id = @to_feed_id
+    );
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD last_error TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_error;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE posts
+ADD content_hash TEXT NOT NULL DEFAULT '',
+ADD revisions INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts
+SET content_hash = encode(sha256(convert_to(title || E'\n' || description, 'UTF8')), 'hex');
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content_hash,
+DROP COLUMN revisions;
\ No newline at end of file
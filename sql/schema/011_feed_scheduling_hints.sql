@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD ttl_minutes INTEGER NULL,
+ADD skip_hours INTEGER[] NOT NULL DEFAULT '{}',
+ADD skip_days TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN ttl_minutes,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days;
\ No newline at end of file
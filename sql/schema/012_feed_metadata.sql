@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD title TEXT NOT NULL DEFAULT '',
+ADD description TEXT NOT NULL DEFAULT '',
+ADD site_url TEXT NOT NULL DEFAULT '',
+ADD language TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN title,
+DROP COLUMN description,
+DROP COLUMN site_url,
+DROP COLUMN language;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD last_status_code INTEGER NULL,
+ADD disabled_at TIMESTAMP NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_status_code,
+DROP COLUMN disabled_at;
\ No newline at end of file
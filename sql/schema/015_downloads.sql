@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE downloads(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE NOT NULL,
+    enclosure_id UUID REFERENCES enclosures(id) ON DELETE CASCADE NOT NULL,
+    UNIQUE (user_id, enclosure_id)
+);
+
+-- +goose Down
+DROP TABLE downloads;
\ No newline at end of file
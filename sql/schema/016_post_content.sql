@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts
+ADD content TEXT NOT NULL DEFAULT '';
+
+UPDATE posts
+SET content_hash = encode(sha256(convert_to(title || E'\n' || description || E'\n' || content, 'UTF8')), 'hex');
+
+-- +goose Down
+UPDATE posts
+SET content_hash = encode(sha256(convert_to(title || E'\n' || description, 'UTF8')), 'hex');
+
+ALTER TABLE posts
+DROP COLUMN content;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE posts
+ADD author TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE post_categories(
+    post_id UUID REFERENCES posts(id) ON DELETE CASCADE NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY (post_id, name)
+);
+
+-- +goose Down
+DROP TABLE post_categories;
+
+ALTER TABLE posts
+DROP COLUMN author;
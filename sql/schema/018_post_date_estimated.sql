@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE posts
+ADD published_at_estimated BOOLEAN NOT NULL DEFAULT false;
+
+-- posts whose date couldn't be parsed were stored at the zero time
+UPDATE posts
+SET published_at = created_at,
+    published_at_estimated = true
+WHERE published_at <= '0001-01-01';
+
+-- +goose Down
+UPDATE posts
+SET published_at = '0001-01-01'
+WHERE published_at_estimated;
+
+ALTER TABLE posts
+DROP COLUMN published_at_estimated;
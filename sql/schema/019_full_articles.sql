@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD full_article BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE posts
+ADD article TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN article;
+
+ALTER TABLE feeds
+DROP COLUMN full_article;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- hashes are now taken from sanitized html, so the stored ones would make
+-- every existing post look changed. An empty hash marks the post for a
+-- backfill on its next fetch instead of counting as a revision.
+UPDATE posts
+SET content_hash = '';
+
+-- +goose Down
+UPDATE posts
+SET content_hash = encode(sha256(convert_to(title || E'\n' || description || E'\n' || content, 'UTF8')), 'hex');